@@ -0,0 +1,149 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines shown before and after each change in a unified diff.
+const diffContext = 3
+
+// diffLine is a line of a line-by-line diff.
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string // the line, including any trailing newline
+}
+
+// unifiedDiff returns a unified diff which changes text a into text b, or the empty string if they are equal.
+func unifiedDiff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	// diff the texts line by line by encoding each distinct line as a rune
+	lineRunes := map[string]rune{}
+	runeLines := []string{}
+	encode := func(text string) []rune {
+		runes := []rune{}
+		for _, l := range strings.SplitAfter(text, "\n") {
+			if l == "" {
+				continue
+			}
+			r, ok := lineRunes[l]
+			if !ok {
+				r = lineRune(len(runeLines))
+				lineRunes[l] = r
+				runeLines = append(runeLines, l)
+			}
+			runes = append(runes, r)
+		}
+		return runes
+	}
+	ra, rb := encode(a), encode(b)
+	lines := []diffLine{}
+	for _, d := range diffmatchpatch.New().DiffMainRunes(ra, rb, false) {
+		for _, r := range d.Text {
+			lines = append(lines, diffLine{op: d.Type, text: runeLines[runeIndex(r)]})
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	oldLine, newLine := 1, 1 // the line numbers of lines[i] in a and b
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// extend the hunk until more than twice the context of unchanged lines follow its last change
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		lastChange := i
+		for end := i; end < len(lines) && end-lastChange <= 2*diffContext+1; end++ {
+			if lines[end].op != diffmatchpatch.DiffEqual {
+				lastChange = end
+			}
+		}
+		end := lastChange + 1 + diffContext
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		oldStart, newStart := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		var hunk strings.Builder
+		for _, l := range lines[start:end] {
+			switch l.op {
+			case diffmatchpatch.DiffEqual:
+				hunk.WriteString(" ")
+				oldCount++
+				newCount++
+			case diffmatchpatch.DiffDelete:
+				hunk.WriteString("-")
+				oldCount++
+			case diffmatchpatch.DiffInsert:
+				hunk.WriteString("+")
+				newCount++
+			}
+			hunk.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				hunk.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		out.WriteString(hunk.String())
+
+		for _, l := range lines[i:end] {
+			if l.op != diffmatchpatch.DiffInsert {
+				oldLine++
+			}
+			if l.op != diffmatchpatch.DiffDelete {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// lineRune returns the rune which encodes the line with the given index, skipping the surrogate code points, which
+// are not valid runes.
+func lineRune(i int) rune {
+	if i >= 0xd800 {
+		i += 0x800
+	}
+	return rune(i)
+}
+
+// runeIndex returns the index of the line encoded by a rune.
+func runeIndex(r rune) int {
+	if r >= 0xe000 {
+		r -= 0x800
+	}
+	return int(r)
+}
+
+// hunkRange formats the start and count of lines of a hunk in the manner of diff -u.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
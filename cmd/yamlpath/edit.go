@@ -0,0 +1,263 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+// runEdit runs the set or delete command with the given arguments, excluding the command, and returns the exit
+// status.
+func runEdit(command string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("yamlpath "+command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	inPlace := flags.Bool("i", false, "edit the files in place")
+	dryRun := flags.Bool("n", false, "print a unified diff of the changes instead of the edited documents and do not change any files")
+	indent := flags.Int("indent", 2, "the number of `spaces` by which to indent the edited documents")
+	operands := "expression"
+	if command == "set" {
+		operands = "expression value"
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: yamlpath %s [-i] [-n] [-indent spaces] %s [file ...]\n", command, operands)
+		flags.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return exitMatch
+		}
+		return exitSyntaxError
+	}
+	n := 1
+	if command == "set" {
+		n = 2
+	}
+	if len(positional) < n {
+		flags.Usage()
+		return exitSyntaxError
+	}
+	files := positional[n:]
+	if len(files) == 0 {
+		if *inPlace && !*dryRun {
+			fmt.Fprintln(stderr, "yamlpath: -i requires files")
+			return exitSyntaxError
+		}
+		files = []string{stdinName}
+	}
+	if *indent < 1 {
+		fmt.Fprintf(stderr, "yamlpath: invalid indent %d\n", *indent)
+		return exitSyntaxError
+	}
+
+	path, err := yamlpath.NewPath(positional[0])
+	if err != nil {
+		reportInvalidExpression(stderr, err)
+		return exitSyntaxError
+	}
+
+	edit := path.Delete
+	if command == "set" {
+		var value yaml.Node
+		if err := yaml.Unmarshal([]byte(positional[1]), &value); err != nil {
+			fmt.Fprintf(stderr, "yamlpath: invalid value: %v\n", err)
+			return exitSyntaxError
+		}
+		if value.Kind == 0 {
+			value = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"} // an empty document
+		}
+		edit = func(root *yaml.Node) error {
+			return path.Set(root, &value)
+		}
+	}
+
+	matched, failed, invalid := false, false, false
+	for _, file := range files {
+		name, original, err := readInput(file, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "yamlpath: %v\n", err)
+			failed = true
+			continue
+		}
+
+		edited, changed, err := editDocuments(name, original, path, edit, *indent)
+		if err != nil {
+			fmt.Fprintf(stderr, "yamlpath: %v\n", err)
+			var e *editError
+			if errors.As(err, &e) {
+				invalid = true
+			} else {
+				failed = true
+			}
+			continue
+		}
+		matched = matched || changed
+
+		switch {
+		case *dryRun:
+			_, err = io.WriteString(stdout, unifiedDiff(name, string(original), string(edited)))
+		case *inPlace:
+			if !bytes.Equal(original, edited) {
+				err = writeFile(file, edited)
+			}
+		default:
+			_, err = stdout.Write(edited)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "yamlpath: %v\n", err)
+			failed = true
+		}
+	}
+
+	switch {
+	case failed:
+		return exitIOError
+	case invalid:
+		return exitSyntaxError
+	case matched:
+		return exitMatch
+	default:
+		return exitNoMatch
+	}
+}
+
+// parseInterspersed parses flags which may be interspersed with positional arguments, up to any "--", and returns
+// the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// readInput reads the given file, or standard input if the file is "-", and returns its name and content.
+func readInput(file string, stdin io.Reader) (string, []byte, error) {
+	if file == stdinName {
+		data, err := ioutil.ReadAll(stdin)
+		return "standard input", data, err
+	}
+	data, err := ioutil.ReadFile(file)
+	return file, data, err
+}
+
+// writeFile replaces the content of a file, preserving its permissions. The data is written to a temporary file in
+// the same directory which is then renamed over the file, so that the file is never left partially written. If the
+// file is a symbolic link, the file it refers to is replaced.
+func writeFile(file string, data []byte) (err error) {
+	target, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// editError is an error returned by an edit itself, such as an attempt to delete the root node, rather than an error
+// reading or parsing a file.
+type editError struct {
+	err error
+}
+
+func (e *editError) Error() string {
+	return e.err.Error()
+}
+
+// editDocuments applies an edit to each document in the given data which the path matches and returns the edited
+// data and whether any document was matched. If no document was matched, the data is returned unchanged rather
+// than re-encoded.
+func editDocuments(name string, data []byte, path *yamlpath.Path, edit func(*yaml.Node) error, indent int) ([]byte, bool, error) {
+	docs := []*yaml.Node{}
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := d.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, false, fmt.Errorf("%s: %v", name, err)
+		}
+		docs = append(docs, &doc)
+	}
+
+	matched := false
+	for _, doc := range docs {
+		exists, err := path.Exists(doc)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %v", name, err)
+		}
+		if !exists {
+			continue
+		}
+		matched = true
+		if err := edit(doc); err != nil {
+			return nil, false, &editError{fmt.Errorf("%s: %v", name, err)}
+		}
+	}
+	if !matched {
+		return data, false, nil
+	}
+
+	var buf bytes.Buffer
+	e := yaml.NewEncoder(&buf)
+	e.SetIndent(indent)
+	for _, doc := range docs {
+		if err := e.Encode(doc); err != nil {
+			return nil, false, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		return nil, false, fmt.Errorf("%s: %v", name, err)
+	}
+	return buf.Bytes(), true, nil
+}
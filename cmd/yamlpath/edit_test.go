@@ -0,0 +1,321 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunEdit(t *testing.T) {
+	deployment := `# the deployment
+spec:
+  replicas: 1 # just one
+  containers:
+    - name: nginx
+      image: "nginx:1.19" # pinned
+    - name: sidecar
+      image: envoy:1.16
+  defaults: &defaults {pull: always}
+  override: *defaults
+`
+	service := `spec:
+  ports:
+    - port: 80
+`
+
+	cases := []struct {
+		name           string
+		args           []string // the files deployment.yaml and service.yaml are in the current directory
+		stdin          string
+		expectedStdout string
+		expectedStderr string
+		expectedStatus int
+		expectedFiles  map[string]string // the expected content of any changed files
+		focus          bool              // if true, run only tests with focus set to true
+	}{
+		{
+			name:           "set in place",
+			args:           []string{"set", "$..image", "repo/x:1.2", "-i", "deployment.yaml"},
+			expectedStatus: exitMatch,
+			expectedFiles: map[string]string{
+				"deployment.yaml": `# the deployment
+spec:
+  replicas: 1 # just one
+  containers:
+    - name: nginx
+      image: repo/x:1.2 # pinned
+    - name: sidecar
+      image: repo/x:1.2
+  defaults: &defaults {pull: always}
+  override: *defaults
+`,
+			},
+		},
+		{
+			name:           "delete in place",
+			args:           []string{"delete", "-i", "$.spec.containers[?(@.name == 'sidecar')]", "deployment.yaml"},
+			expectedStatus: exitMatch,
+			expectedFiles: map[string]string{
+				"deployment.yaml": `# the deployment
+spec:
+  replicas: 1 # just one
+  containers:
+    - name: nginx
+      image: "nginx:1.19" # pinned
+  defaults: &defaults {pull: always}
+  override: *defaults
+`,
+			},
+		},
+		{
+			name:           "set a mapping in several files",
+			args:           []string{"set", "-i", "$.spec.replicas", "{min: 1, max: 3}", "deployment.yaml", "service.yaml"},
+			expectedStatus: exitMatch,
+			expectedFiles: map[string]string{
+				"deployment.yaml": `# the deployment
+spec:
+  replicas: {min: 1, max: 3} # just one
+  containers:
+    - name: nginx
+      image: "nginx:1.19" # pinned
+    - name: sidecar
+      image: envoy:1.16
+  defaults: &defaults {pull: always}
+  override: *defaults
+`,
+			},
+		},
+		{
+			name: "dry run",
+			args: []string{"set", "-i", "-n", "$..port", "8080", "deployment.yaml", "service.yaml"},
+			expectedStdout: `--- service.yaml
++++ service.yaml
+@@ -1,3 +1,3 @@
+ spec:
+   ports:
+-    - port: 80
++    - port: 8080
+`,
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "print edited documents",
+			args:           []string{"delete", "$.spec.containers", "deployment.yaml", "service.yaml"},
+			expectedStdout: "# the deployment\nspec:\n  replicas: 1 # just one\n  defaults: &defaults {pull: always}\n  override: *defaults\n" + service,
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "standard input",
+			args:           []string{"set", "$.a", "y"},
+			stdin:          "a: x # comment\n---\nb: z\n",
+			expectedStdout: "a: y # comment\n---\nb: z\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "indent",
+			args:           []string{"set", "-indent", "4", "$.a.b", "y"},
+			stdin:          "a:\n  b: x\n",
+			expectedStdout: "a:\n    b: y\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "no match",
+			args:           []string{"delete", "-i", "$.missing", "deployment.yaml"},
+			expectedStatus: exitNoMatch,
+		},
+		{
+			name:           "negative value after --",
+			args:           []string{"set", "-i", "--", "$.spec.replicas", "-1", "deployment.yaml"},
+			expectedStatus: exitMatch,
+			expectedFiles: map[string]string{
+				"deployment.yaml": strings.Replace(deployment, "replicas: 1", "replicas: -1", 1),
+			},
+		},
+		{
+			name:           "invalid edit",
+			args:           []string{"delete", "-i", "$", "deployment.yaml"},
+			expectedStderr: "yamlpath: deployment.yaml: cannot delete the root node\n",
+			expectedStatus: exitSyntaxError,
+			expectedFiles: map[string]string{
+				"deployment.yaml": deployment,
+			},
+		},
+		{
+			name:           "in place without files",
+			args:           []string{"set", "-i", "$.a", "b"},
+			expectedStderr: "yamlpath: -i requires files\n",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "missing value",
+			args:           []string{"set", "$.a"},
+			expectedStderr: "Usage: yamlpath set",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "invalid value",
+			args:           []string{"set", "$.a", "[", "deployment.yaml"},
+			expectedStderr: "yamlpath: invalid value: yaml:",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "invalid expression",
+			args:           []string{"delete", "$.a[", "deployment.yaml"},
+			expectedStderr: "yamlpath: invalid expression: unmatched [",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "missing file",
+			args:           []string{"set", "-i", "$.spec.replicas", "2", "missing.yaml", "deployment.yaml"},
+			expectedStderr: "no such file or directory",
+			expectedStatus: exitIOError,
+			expectedFiles: map[string]string{
+				"deployment.yaml": strings.Replace(deployment, "replicas: 1", "replicas: 2", 1),
+			},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "yamlpath")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			files := map[string]string{
+				"deployment.yaml": deployment,
+				"service.yaml":    service,
+			}
+			for name, content := range files {
+				err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+				require.NoError(t, err)
+			}
+			require.NoError(t, os.Chdir(dir))
+			defer os.Chdir(wd)
+
+			var stdout, stderr bytes.Buffer
+			status := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			require.Equal(t, tc.expectedStatus, status)
+			require.Equal(t, tc.expectedStdout, stdout.String())
+			if tc.expectedStderr == "" {
+				require.Empty(t, stderr.String())
+			} else {
+				require.Contains(t, stderr.String(), tc.expectedStderr)
+			}
+
+			for name, content := range files {
+				if expected, ok := tc.expectedFiles[name]; ok {
+					content = expected
+				}
+				actual, err := ioutil.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				require.Equal(t, content, string(actual), "content of %s", name)
+			}
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	cases := []struct {
+		name    string
+		mode    os.FileMode
+		symlink bool // if true, write through a symbolic link to the file
+		focus   bool // if true, run only tests with focus set to true
+	}{
+		{
+			name: "file",
+			mode: 0644,
+		},
+		{
+			name: "file with restricted permissions",
+			mode: 0600,
+		},
+		{
+			name:    "symbolic link",
+			mode:    0640,
+			symlink: true,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "yamlpath")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "a.yaml")
+			require.NoError(t, ioutil.WriteFile(file, []byte("a: 1\n"), tc.mode))
+			require.NoError(t, os.Chmod(file, tc.mode)) // not subject to the umask
+			written := file
+			if tc.symlink {
+				written = filepath.Join(dir, "link.yaml")
+				require.NoError(t, os.Symlink("a.yaml", written))
+			}
+
+			require.NoError(t, writeFile(written, []byte("a: 2\n")))
+
+			actual, err := ioutil.ReadFile(file)
+			require.NoError(t, err)
+			require.Equal(t, "a: 2\n", string(actual))
+			info, err := os.Stat(file)
+			require.NoError(t, err)
+			require.Equal(t, tc.mode, info.Mode().Perm())
+			if tc.symlink {
+				info, err := os.Lstat(written)
+				require.NoError(t, err)
+				require.Equal(t, os.ModeSymlink, info.Mode()&os.ModeSymlink, "symbolic link was replaced")
+			}
+
+			// no temporary files are left behind
+			entries, err := ioutil.ReadDir(dir)
+			require.NoError(t, err)
+			expectedEntries := 1
+			if tc.symlink {
+				expectedEntries = 2
+			}
+			require.Len(t, entries, expectedEntries)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
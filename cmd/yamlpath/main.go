@@ -0,0 +1,201 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Command yamlpath applies YAML JSONPath expressions to YAML (or JSON) documents read from files or standard input
+// and prints the matching nodes.
+//
+// Usage:
+//
+//	yamlpath [-o format] expression [file ...]
+//	yamlpath [-o format] -e expression [-e expression ...] [file ...]
+//	yamlpath set [-i] [-n] [-indent spaces] expression value [file ...]
+//	yamlpath delete [-i] [-n] [-indent spaces] expression [file ...]
+//
+// Each document of each file (or of standard input if no files, or the file "-", are given) is searched using each
+// expression in turn. The output format is one of:
+//
+//	yaml  each matching node as a YAML document (the default)
+//	json  each matching node as JSON on a single line
+//	raw   the value of each matching scalar node, otherwise as for json
+//	path  the normalized path of each matching node
+//
+// The set command replaces each matching node with the given value, which is parsed as YAML, and the delete command
+// removes each matching node. The edited documents are printed or, with -i, written back to the files, which are
+// left untouched if nothing matched. With -n, a unified diff of the changes is printed instead. Comments, key order,
+// anchors, and quoting styles are preserved as far as gopkg.in/yaml.v3 preserves them, but the documents are
+// re-indented.
+//
+// The exit status is 0 if any node matched, 1 if no node matched, 2 if an expression or the command line is
+// invalid, and 3 if an input could not be read or parsed or the output could not be written.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	exitMatch       = 0
+	exitNoMatch     = 1
+	exitSyntaxError = 2
+	exitIOError     = 3
+)
+
+// stdinName is the name which denotes standard input in place of a file.
+const stdinName = "-"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments, excluding the command name, and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && (args[0] == "set" || args[0] == "delete") {
+		return runEdit(args[0], args[1:], stdin, stdout, stderr)
+	}
+
+	flags := flag.NewFlagSet("yamlpath", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var exprs expressions
+	flags.Var(&exprs, "e", "path `expression` to evaluate (may be repeated)")
+	format := flags.String("o", "yaml", "output `format`: yaml, json, raw, or path")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `Usage: yamlpath [-o format] expression [file ...]
+       yamlpath [-o format] -e expression [-e expression ...] [file ...]
+       yamlpath set [-i] [-n] [-indent spaces] expression value [file ...]
+       yamlpath delete [-i] [-n] [-indent spaces] expression [file ...]
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitMatch
+		}
+		return exitSyntaxError
+	}
+
+	files := flags.Args()
+	if len(exprs) == 0 {
+		if len(files) == 0 {
+			flags.Usage()
+			return exitSyntaxError
+		}
+		exprs, files = expressions{files[0]}, files[1:]
+	}
+	if len(files) == 0 {
+		files = []string{stdinName}
+	}
+
+	out, err := newPrinter(*format, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "yamlpath: %v\n", err)
+		return exitSyntaxError
+	}
+
+	paths := []*yamlpath.Path{}
+	for _, expr := range exprs {
+		p, err := yamlpath.NewPath(expr)
+		if err != nil {
+			reportInvalidExpression(stderr, err)
+			return exitSyntaxError
+		}
+		paths = append(paths, p)
+	}
+
+	matched, failed := false, false
+	for _, file := range files {
+		err := forEachDocument(file, stdin, func(doc *yaml.Node) error {
+			for _, p := range paths {
+				locations, err := p.FindLocations(doc)
+				if err != nil {
+					return err
+				}
+				for _, l := range locations {
+					matched = true
+					if err := out.print(l); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "yamlpath: %v\n", err)
+			failed = true
+		}
+	}
+	if err := out.close(); err != nil {
+		fmt.Fprintf(stderr, "yamlpath: %v\n", err)
+		failed = true
+	}
+
+	switch {
+	case failed:
+		return exitIOError
+	case matched:
+		return exitMatch
+	default:
+		return exitNoMatch
+	}
+}
+
+// reportInvalidExpression reports an error returned by yamlpath.NewPath, showing where any syntax error occurred.
+func reportInvalidExpression(stderr io.Writer, err error) {
+	fmt.Fprintf(stderr, "yamlpath: invalid expression: %v\n", err)
+	var se *yamlpath.SyntaxError
+	if errors.As(err, &se) {
+		fmt.Fprintln(stderr, se.Excerpt())
+	}
+}
+
+// expressions is a flag.Value which accumulates the expressions given by repeated flags.
+type expressions []string
+
+func (e *expressions) String() string {
+	return strings.Join(*e, " ")
+}
+
+func (e *expressions) Set(expr string) error {
+	*e = append(*e, expr)
+	return nil
+}
+
+// forEachDocument calls f with each document of the given file, or of standard input if the file is "-".
+func forEachDocument(file string, stdin io.Reader, f func(doc *yaml.Node) error) error {
+	name := file
+	r := stdin
+	if file == stdinName {
+		name = "standard input"
+	} else {
+		fr, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer fr.Close()
+		r = fr
+	}
+
+	d := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		if err := d.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := f(&doc); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yamlpath")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deployment := filepath.Join(dir, "deployment.yaml")
+	err = ioutil.WriteFile(deployment, []byte(`metadata:
+  name: web # the name
+spec:
+  containers:
+  - name: nginx
+    image: nginx:1.19
+  - name: sidecar
+    image: envoy:1.16
+---
+metadata:
+  name: db
+`), 0644)
+	require.NoError(t, err)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	err = ioutil.WriteFile(invalid, []byte("a: [\n"), 0644)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name           string
+		args           []string
+		stdin          string
+		expectedStdout string
+		expectedStderr string
+		expectedStatus int
+		focus          bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:           "yaml output",
+			args:           []string{"$.spec.containers[0]", deployment},
+			expectedStdout: "name: nginx\nimage: nginx:1.19\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "multiple documents",
+			args:           []string{"-o", "raw", "$.metadata.name", deployment},
+			expectedStdout: "web\ndb\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "multiple expressions",
+			args:           []string{"-o", "raw", "-e", "$..image", "-e", "$.metadata.name", deployment},
+			expectedStdout: "nginx:1.19\nenvoy:1.16\nweb\ndb\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "yaml output of several nodes",
+			args:           []string{"$..containers[*].name", deployment},
+			expectedStdout: "nginx\n---\nsidecar\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "json output",
+			args:           []string{"-o", "json", "$.spec"},
+			stdin:          `{"spec": {"replicas": 2, "ports": [80, 443], "host": null, "tls": true, "path": "</>"}}`,
+			expectedStdout: `{"replicas":2,"ports":[80,443],"host":null,"tls":true,"path":"</>"}` + "\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "raw output of non-scalar",
+			args:           []string{"-o", "raw", "$.a"},
+			stdin:          "a: {b: x, c: [1]}\n",
+			expectedStdout: `{"b":"x","c":[1]}` + "\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "path output",
+			args:           []string{"-o", "path", "$..image", deployment},
+			expectedStdout: "$['spec']['containers'][0]['image']\n$['spec']['containers'][1]['image']\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "standard input as a file",
+			args:           []string{"-o", "raw", "$.a", "-"},
+			stdin:          "a: x\n",
+			expectedStdout: "x\n",
+			expectedStatus: exitMatch,
+		},
+		{
+			name:           "no match",
+			args:           []string{"$.missing", deployment},
+			expectedStatus: exitNoMatch,
+		},
+		{
+			name:           "empty input",
+			args:           []string{"$"},
+			expectedStatus: exitNoMatch,
+		},
+		{
+			name:           "syntax error",
+			args:           []string{"$.a[", deployment},
+			expectedStderr: "yamlpath: invalid expression: unmatched [ at position 4, following \".a[\"\n$.a[\n    ^\n",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "missing expression",
+			args:           []string{},
+			expectedStderr: "Usage: yamlpath",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "invalid output format",
+			args:           []string{"-o", "xml", "$"},
+			expectedStderr: `yamlpath: invalid output format "xml": must be yaml, json, raw, or path` + "\n",
+			expectedStatus: exitSyntaxError,
+		},
+		{
+			name:           "missing file",
+			args:           []string{"-o", "raw", "$.metadata.name", filepath.Join(dir, "missing.yaml"), deployment},
+			expectedStdout: "web\ndb\n",
+			expectedStderr: "no such file or directory",
+			expectedStatus: exitIOError,
+		},
+		{
+			name:           "invalid YAML",
+			args:           []string{"$", invalid},
+			expectedStderr: "yamlpath: " + invalid + ": yaml: line 1: did not find expected node content",
+			expectedStatus: exitIOError,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			require.Equal(t, tc.expectedStatus, status)
+			require.Equal(t, tc.expectedStdout, stdout.String())
+			if tc.expectedStderr == "" {
+				require.Empty(t, stderr.String())
+			} else {
+				require.Contains(t, stderr.String(), tc.expectedStderr)
+			}
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
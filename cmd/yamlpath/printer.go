@@ -0,0 +1,163 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+// printer prints matching nodes in an output format.
+type printer interface {
+	print(l yamlpath.Location) error
+	close() error
+}
+
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "yaml":
+		e := yaml.NewEncoder(w)
+		e.SetIndent(2)
+		return &yamlPrinter{e: e}, nil
+	case "json":
+		return &linePrinter{w: w, line: jsonLine}, nil
+	case "raw":
+		return &linePrinter{w: w, line: rawLine}, nil
+	case "path":
+		return &linePrinter{w: w, line: func(l yamlpath.Location) (string, error) {
+			return l.Path, nil
+		}}, nil
+	default:
+		return nil, fmt.Errorf("invalid output format %q: must be yaml, json, raw, or path", format)
+	}
+}
+
+// yamlPrinter prints each node as a YAML document.
+type yamlPrinter struct {
+	e       *yaml.Encoder
+	encoded bool // whether any node has been encoded
+}
+
+func (p *yamlPrinter) print(l yamlpath.Location) error {
+	p.encoded = true
+	return p.e.Encode(dealias(l.Node))
+}
+
+func (p *yamlPrinter) close() error {
+	if !p.encoded {
+		return nil // closing an encoder which has encoded nothing is an error
+	}
+	return p.e.Close()
+}
+
+// linePrinter prints each node on a single line.
+type linePrinter struct {
+	w    io.Writer
+	line func(l yamlpath.Location) (string, error)
+}
+
+func (p *linePrinter) print(l yamlpath.Location) error {
+	s, err := p.line(l)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w, s)
+	return err
+}
+
+func (p *linePrinter) close() error {
+	return nil
+}
+
+func jsonLine(l yamlpath.Location) (string, error) {
+	var b bytes.Buffer
+	if err := writeJSON(&b, l.Node); err != nil {
+		return "", fmt.Errorf("%s: %v", l.Path, err)
+	}
+	return b.String(), nil
+}
+
+func rawLine(l yamlpath.Location) (string, error) {
+	if n := dealias(l.Node); n.Kind == yaml.ScalarNode {
+		return n.Value, nil
+	}
+	return jsonLine(l)
+}
+
+// writeJSON writes a node as compact JSON, preserving the order of mapping keys.
+func writeJSON(b *bytes.Buffer, n *yaml.Node) error {
+	n = dealias(n)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			b.WriteString("null")
+			return nil
+		}
+		return writeJSON(b, n.Content[0])
+
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, c); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSONValue(b, dealias(n.Content[i]).Value); err != nil {
+				return err
+			}
+			b.WriteByte(':')
+			if err := writeJSON(b, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+		return nil
+
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			v = n.Value
+		}
+		return writeJSONValue(b, v)
+	}
+}
+
+// writeJSONValue writes a Go value as JSON without escaping HTML characters.
+func writeJSONValue(b *bytes.Buffer, v interface{}) error {
+	var vb bytes.Buffer
+	e := json.NewEncoder(&vb)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return err
+	}
+	b.Write(bytes.TrimSuffix(vb.Bytes(), []byte("\n")))
+	return nil
+}
+
+func dealias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
@@ -3,7 +3,6 @@ module github.com/vmware-labs/yaml-jsonpath
 go 1.13
 
 require (
-	github.com/dprotaso/go-yit v0.0.0-20220510233725-9ba8df137936
 	github.com/sergi/go-diff v1.3.1
 	github.com/stretchr/testify v1.8.4
 	gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// arithmeticFilterScanner returns a filter scanner for an arithmetic expression. The operator is applied to each
+// value produced by its operand or, for a binary operator, to each pair of values produced by its operands. Values
+// for which the operator is undefined, such as a number and a string, produce no result.
+func arithmeticFilterScanner(n *filterNode) filterScanner {
+	op := n.lexeme.typ
+	if op == lexemeFilterUnaryMinus {
+		operand := newFilterScanner(n.children[0])
+		return func(loc *location, root *yaml.Node) []typedValue {
+			results := []typedValue{}
+			for _, v := range operand(loc, root) {
+				if r, ok := v.negate(); ok {
+					results = append(results, r)
+				}
+			}
+			return results
+		}
+	}
+
+	lhs := newFilterScanner(n.children[0])
+	rhs := newFilterScanner(n.children[1])
+	return func(loc *location, root *yaml.Node) []typedValue {
+		results := []typedValue{}
+		rs := rhs(loc, root)
+		for _, l := range lhs(loc, root) {
+			for _, r := range rs {
+				if v, ok := l.arithmetic(op, r); ok {
+					results = append(results, v)
+				}
+			}
+		}
+		return results
+	}
+}
+
+// arithmetic applies a binary arithmetic operator to two values. Two integers produce an integer, except that an
+// inexact division or an overflow produces a float, and any other pair of numbers produces a float. Two strings may
+// be concatenated using +. The result is undefined for any other values and for division or remainder by zero.
+func (v typedValue) arithmetic(op lexemeType, w typedValue) (typedValue, bool) {
+	if op == lexemeFilterAdd && v.typ == stringValueType && w.typ == stringValueType {
+		return typedValueOfString(v.val + w.val), true
+	}
+	if !v.typ.isNumeric() || !w.typ.isNumeric() {
+		return typedValue{}, false
+	}
+
+	if v.typ == intValueType && w.typ == intValueType {
+		a, aok := v.int64()
+		b, bok := w.int64()
+		if aok && bok {
+			if r, ok := integerArithmetic(op, a, b); ok {
+				return typedValueOfInt(strconv.FormatInt(r, 10)), true
+			}
+			if b == 0 && (op == lexemeFilterDivide || op == lexemeFilterModulo) {
+				return typedValue{}, false
+			}
+		}
+	}
+
+	a, aok := v.float64()
+	b, bok := w.float64()
+	if !aok || !bok {
+		return typedValue{}, false
+	}
+	var r float64
+	switch op {
+	case lexemeFilterAdd:
+		r = a + b
+	case lexemeFilterSubtract:
+		r = a - b
+	case lexemeFilterMultiply:
+		r = a * b
+	case lexemeFilterDivide:
+		if b == 0 {
+			return typedValue{}, false
+		}
+		r = a / b
+	case lexemeFilterModulo:
+		if b == 0 {
+			return typedValue{}, false
+		}
+		r = math.Mod(a, b)
+	}
+	return floatResult(r)
+}
+
+// integerArithmetic applies a binary arithmetic operator to two integers. It returns false if the result is not an
+// integer, because of overflow or an inexact division, or if the result is undefined.
+func integerArithmetic(op lexemeType, a, b int64) (int64, bool) {
+	switch op {
+	case lexemeFilterAdd:
+		r := a + b
+		return r, (r > a) == (b > 0)
+
+	case lexemeFilterSubtract:
+		r := a - b
+		return r, (r < a) == (b > 0)
+
+	case lexemeFilterMultiply:
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+		r := a * b
+		return r, r/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+
+	case lexemeFilterDivide:
+		if b == 0 || a%b != 0 || a == math.MinInt64 && b == -1 {
+			return 0, false
+		}
+		return a / b, true
+
+	case lexemeFilterModulo:
+		if b == 0 {
+			return 0, false
+		}
+		if b == -1 {
+			return 0, true
+		}
+		return a % b, true
+	}
+	return 0, false
+}
+
+// negate applies unary minus to a value, which must be a number.
+func (v typedValue) negate() (typedValue, bool) {
+	if v.typ == intValueType {
+		if i, ok := v.int64(); ok && i != math.MinInt64 {
+			return typedValueOfInt(strconv.FormatInt(-i, 10)), true
+		}
+	}
+	if !v.typ.isNumeric() {
+		return typedValue{}, false
+	}
+	f, ok := v.float64()
+	if !ok {
+		return typedValue{}, false
+	}
+	return floatResult(-f)
+}
+
+// int64 returns the value of an integer, which may be written in any of the forms allowed by YAML, such as 0x1F.
+func (v typedValue) int64() (int64, bool) {
+	i, err := strconv.ParseInt(strings.ReplaceAll(v.val, "_", ""), 0, 64)
+	return i, err == nil
+}
+
+// float64 returns the value of a number.
+func (v typedValue) float64() (float64, bool) {
+	if v.typ == intValueType {
+		if i, ok := v.int64(); ok {
+			return float64(i), true
+		}
+	}
+	f, err := strconv.ParseFloat(v.val, 64)
+	return f, err == nil
+}
+
+// floatResult returns a float value, unless the given float is infinite or not a number.
+func floatResult(f float64) (typedValue, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return typedValue{}, false
+	}
+	return typedValueOfFloat(strconv.FormatFloat(f, 'g', -1, 64)), true
+}
@@ -0,0 +1,1043 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AST is the abstract syntax tree of a path expression in the syntax described in the README. An AST is produced by
+// Parse, printed in a canonical form by String, and turned into a Path by Compile.
+//
+// A path expression is a sequence of segments, each of which is applied to the nodes matched by the preceding
+// segments. An AST with no segments matches the node to which it is applied.
+type AST struct {
+	Segments []Segment
+}
+
+// Segment is a segment of an AST. It is one of RootSegment, ChildSegment, WildcardSegment, SubscriptSegment,
+// FilterSegment, UnionSegment, RecursiveDescentSegment, PropertyNameSegment, TagSegment, ParentSegment, or
+// AncestorsSegment.
+type Segment interface {
+	// String returns the canonical form of the segment.
+	String() string
+
+	segment()
+}
+
+// RootSegment matches the root node, or the content of the root node if it is a document node. It is written `$`
+// and is implied if a path expression does not begin with `$`.
+type RootSegment struct{}
+
+// ChildSegment matches the values of the mapping keys with the given names. Its canonical form uses bracket
+// notation, so `.a` and `["a"]` are both written `['a']`.
+type ChildSegment struct {
+	Names []string // the names, without quotes or escapes
+}
+
+// WildcardSegment matches all the values of a mapping and all the items of a sequence. It is written `[*]`
+// (or `.*`).
+type WildcardSegment struct{}
+
+// SubscriptSegment matches the items of a sequence selected by one or more indices or slices, for example `[0]`,
+// `[1:5:2]`, or `[0,2,-1:]`.
+type SubscriptSegment struct {
+	Subscripts []Subscript
+}
+
+// Subscript is a member of a SubscriptSegment: either an index or, if Slice is non-nil, a slice.
+type Subscript struct {
+	Index int    // the index, negative indices counting back from the end of the sequence
+	Slice *Slice // the slice, or nil if the subscript is an index
+}
+
+// Slice is a slice `start:end:step` in which each of the values may be omitted.
+type Slice struct {
+	Start, End, Step *int // nil if omitted
+}
+
+// FilterSegment matches the nodes which satisfy a filter expression: the items of a sequence or, if the node is
+// not a sequence, the node itself. When a FilterSegment immediately follows a RecursiveDescentSegment, each node is
+// filtered itself, whether or not it is a sequence. It is written `[?(...)]`.
+type FilterSegment struct {
+	Filter *FilterExpr // nil if the filter expression is empty
+}
+
+// UnionSegment matches the nodes matched by each of its members in turn, so that a node matched by more than one
+// member is matched more than once. Each member is a ChildSegment, WildcardSegment, SubscriptSegment, or
+// FilterSegment. It is written with the members, without their brackets, separated by commas, for example
+// `[0,'name',2:4,?(@.x)]`.
+type UnionSegment struct {
+	Members []Segment
+}
+
+// RecursiveDescentSegment matches a node and all its descendants. It is written `..` and must be followed by
+// another segment, so that `..a` is the RecursiveDescentSegment followed by a ChildSegment.
+type RecursiveDescentSegment struct{}
+
+// PropertyNameSegment matches the keys, rather than the values, of a mapping: either the keys with the given names,
+// or, if Wildcard is true, all the keys, or, if Filter is non-nil, the keys whose values satisfy Filter. It is written
+// `['a']~`, `[*]~`, or `[?(...)]~`. Any following segments are applied to the keys.
+type PropertyNameSegment struct {
+	Names    []string // the names, without quotes or escapes
+	Wildcard bool
+	Filter   *FilterExpr
+}
+
+// TagSegment matches a node if the node has the given tag, for example `!Ref` or `!!str`. Tags are compared in their
+// short form (see yaml.Node.ShortTag), so `!!int` matches integers which are not explicitly tagged. It is written
+// `[!Ref]`, so that `..[!Ref]` matches every node tagged `!Ref`.
+type TagSegment struct {
+	Tag string // the tag, including its leading "!"
+}
+
+// ParentSegment matches the parent of a node, that is, the mapping or sequence whose content includes the node. It is
+// written `^`. The root node has no parent.
+type ParentSegment struct{}
+
+// AncestorsSegment matches the ancestors of a node, nearest first. It is written `^*`.
+type AncestorsSegment struct{}
+
+func (RootSegment) segment()             {}
+func (ChildSegment) segment()            {}
+func (WildcardSegment) segment()         {}
+func (SubscriptSegment) segment()        {}
+func (FilterSegment) segment()           {}
+func (UnionSegment) segment()            {}
+func (RecursiveDescentSegment) segment() {}
+func (PropertyNameSegment) segment()     {}
+func (TagSegment) segment()              {}
+func (ParentSegment) segment()           {}
+func (AncestorsSegment) segment()        {}
+
+// String returns the canonical form of the AST, which Parse parses into an equivalent AST.
+func (a *AST) String() string {
+	var b strings.Builder
+	for _, s := range a.Segments {
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+func (RootSegment) String() string {
+	return root
+}
+
+func (s ChildSegment) String() string {
+	return leftBracket + quoteNames(s.Names) + rightBracket
+}
+
+func (WildcardSegment) String() string {
+	return "[*]"
+}
+
+func (s SubscriptSegment) String() string {
+	return leftBracket + s.subscript() + rightBracket
+}
+
+// subscript returns the subscripts of the segment in the form accepted by parseSubscripts.
+func (s SubscriptSegment) subscript() string {
+	members := []string{}
+	for _, sub := range s.Subscripts {
+		members = append(members, sub.String())
+	}
+	return strings.Join(members, ",")
+}
+
+func (s Subscript) String() string {
+	if s.Slice == nil {
+		return strconv.Itoa(s.Index)
+	}
+	bound := func(b *int) string {
+		if b == nil {
+			return ""
+		}
+		return strconv.Itoa(*b)
+	}
+	str := bound(s.Slice.Start) + ":" + bound(s.Slice.End)
+	if s.Slice.Step != nil {
+		str += ":" + bound(s.Slice.Step)
+	}
+	return str
+}
+
+func (s FilterSegment) String() string {
+	return filterBegin + s.Filter.String() + filterEnd
+}
+
+func (s UnionSegment) String() string {
+	members := []string{}
+	for _, m := range s.Members {
+		members = append(members, strings.TrimSuffix(strings.TrimPrefix(m.String(), leftBracket), rightBracket))
+	}
+	return leftBracket + strings.Join(members, unionSeparator) + rightBracket
+}
+
+func (RecursiveDescentSegment) String() string {
+	return recursiveDescent
+}
+
+func (s PropertyNameSegment) String() string {
+	if s.Filter != nil {
+		return filterBegin + s.Filter.String() + filterEnd + propertyName
+	}
+	if s.Wildcard {
+		return "[*]" + propertyName
+	}
+	return leftBracket + quoteNames(s.Names) + rightBracket + propertyName
+}
+
+func (s TagSegment) String() string {
+	return leftBracket + s.Tag + rightBracket
+}
+
+func (ParentSegment) String() string {
+	return parent
+}
+
+func (AncestorsSegment) String() string {
+	return ancestors
+}
+
+// quoteNames returns the given names single-quoted, escaped, and separated by commas.
+func quoteNames(names []string) string {
+	quoted := []string{}
+	for _, name := range names {
+		name = strings.ReplaceAll(name, `\`, `\\`)
+		name = strings.ReplaceAll(name, `'`, `\'`)
+		quoted = append(quoted, "'"+name+"'")
+	}
+	return strings.Join(quoted, ",")
+}
+
+// FilterKind is the kind of a node of a FilterExpr.
+type FilterKind int
+
+const (
+	// FilterOr is the disjunction of its two operands, written `||`.
+	FilterOr FilterKind = iota
+
+	// FilterAnd is the conjunction of its two operands, written `&&`.
+	FilterAnd
+
+	// FilterNot is the negation of its operand, written `!`.
+	FilterNot
+
+	// FilterComparison compares its two operands using its Operator: `==`, `!=`, `<`, `<=`, `>`, `>=`, or `=~`.
+	FilterComparison
+
+	// FilterFunction is a call of the function with the given Name whose arguments are its operands.
+	FilterFunction
+
+	// FilterCurrent is `@` followed by a Path which is applied to the node being filtered.
+	FilterCurrent
+
+	// FilterRoot is `$` followed by a Path which is applied to the root node.
+	FilterRoot
+
+	// FilterInteger is an integer literal.
+	FilterInteger
+
+	// FilterFloat is a floating point literal.
+	FilterFloat
+
+	// FilterString is a string literal, whose Value includes its quotes.
+	FilterString
+
+	// FilterBoolean is `true` or `false`.
+	FilterBoolean
+
+	// FilterNull is `null`.
+	FilterNull
+
+	// FilterRegexp is a regular expression literal, whose Value includes its `/` delimiters.
+	FilterRegexp
+
+	// FilterArithmetic applies its Operator, `+`, `-`, `*`, `/`, or `%`, to its two operands.
+	FilterArithmetic
+
+	// FilterNegation is unary minus applied to its operand, written `-`.
+	FilterNegation
+
+	// FilterKey is the key of the node being filtered, if the node is a mapping value, written `@~`.
+	FilterKey
+)
+
+func (k FilterKind) String() string {
+	switch k {
+	case FilterOr:
+		return "FilterOr"
+	case FilterAnd:
+		return "FilterAnd"
+	case FilterNot:
+		return "FilterNot"
+	case FilterComparison:
+		return "FilterComparison"
+	case FilterFunction:
+		return "FilterFunction"
+	case FilterCurrent:
+		return "FilterCurrent"
+	case FilterRoot:
+		return "FilterRoot"
+	case FilterInteger:
+		return "FilterInteger"
+	case FilterFloat:
+		return "FilterFloat"
+	case FilterString:
+		return "FilterString"
+	case FilterBoolean:
+		return "FilterBoolean"
+	case FilterNull:
+		return "FilterNull"
+	case FilterRegexp:
+		return "FilterRegexp"
+	case FilterArithmetic:
+		return "FilterArithmetic"
+	case FilterNegation:
+		return "FilterNegation"
+	case FilterKey:
+		return "FilterKey"
+	default:
+		return fmt.Sprintf("FilterKind(%d)", int(k))
+	}
+}
+
+// FilterExpr is a node of the parse tree of a filter expression. Only the fields corresponding to the node's Kind
+// are used.
+type FilterExpr struct {
+	Kind     FilterKind
+	Operator string        // FilterComparison and FilterArithmetic: the operator
+	Name     string        // FilterFunction: the name of the function
+	Path     *AST          // FilterCurrent and FilterRoot: the path following `@` or `$`, without a RootSegment
+	Value    string        // literals: the literal as written
+	Operands []*FilterExpr // FilterOr, FilterAnd, FilterNot, FilterComparison, FilterFunction, FilterArithmetic, and FilterNegation
+}
+
+// String returns the canonical form of the filter expression, with single spaces around binary operators and
+// only the brackets needed to preserve the structure of the parse tree.
+func (f *FilterExpr) String() string {
+	if f == nil {
+		return ""
+	}
+	operand := func(i int) *FilterExpr {
+		if i < len(f.Operands) {
+			return f.Operands[i]
+		}
+		return nil
+	}
+	// bracketed returns the given operand, bracketed if it is a logical operator of lower precedence than f or of
+	// the same precedence but on the right, where brackets are needed to preserve the association.
+	bracketed := func(i int) string {
+		o := operand(i)
+		if o == nil {
+			return ""
+		}
+		if o.Kind == FilterOr && (f.Kind != FilterOr || i > 0) ||
+			o.Kind == FilterAnd && (f.Kind == FilterNot || f.Kind == FilterAnd && i > 0) {
+			return filterOpenBracket + o.String() + filterCloseBracket
+		}
+		return o.String()
+	}
+	// arithmeticOperand returns the given operand, bracketed if it binds less tightly than f or, on the right, as
+	// tightly as f. A numeric literal is bracketed after unary minus so that it is not read as a negative literal.
+	arithmeticOperand := func(i int) string {
+		o := operand(i)
+		if o == nil {
+			return ""
+		}
+		if o.precedence() < f.precedence() || o.precedence() == f.precedence() && i > 0 ||
+			f.Kind == FilterNegation && (o.Kind == FilterInteger || o.Kind == FilterFloat) {
+			return filterOpenBracket + o.String() + filterCloseBracket
+		}
+		return o.String()
+	}
+
+	switch f.Kind {
+	case FilterOr:
+		return bracketed(0) + " " + filterDisjunction + " " + bracketed(1)
+	case FilterAnd:
+		return bracketed(0) + " " + filterConjunction + " " + bracketed(1)
+	case FilterNot:
+		return filterNot + bracketed(0)
+	case FilterComparison:
+		return operand(0).String() + " " + f.Operator + " " + operand(1).String()
+	case FilterFunction:
+		args := []string{}
+		for _, o := range f.Operands {
+			args = append(args, o.String())
+		}
+		return f.Name + filterOpenBracket + strings.Join(args, filterArgumentSeparator+" ") + filterCloseBracket
+	case FilterArithmetic:
+		return arithmeticOperand(0) + " " + f.Operator + " " + arithmeticOperand(1)
+	case FilterNegation:
+		return filterSubtract + arithmeticOperand(0)
+	case FilterCurrent:
+		return filterAt + f.Path.String()
+	case FilterRoot:
+		return root + f.Path.String()
+	case FilterKey:
+		return filterKey
+	default:
+		return f.Value
+	}
+}
+
+// precedence returns the precedence of an arithmetic operator, or a higher precedence for a term, or a lower
+// precedence for a logical operator or comparison.
+func (f *FilterExpr) precedence() int {
+	switch f.Kind {
+	case FilterOr, FilterAnd, FilterNot, FilterComparison:
+		return 0
+	case FilterArithmetic:
+		if f.Operator == filterAdd || f.Operator == filterSubtract {
+			return 1
+		}
+		return 2
+	case FilterNegation:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Parse parses a path expression, in the syntax described in the README, into an AST. Options which affect parsing,
+// such as WithFunction, are respected. The RFC9535 option is not supported.
+func Parse(path string, opts ...Option) (*AST, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return parse(path, o)
+}
+
+// Compile constructs a Path from an AST using the given options. The RFC9535 option is not supported.
+func Compile(ast *AST, opts ...Option) (*Path, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	p, err := compileAST(ast, o)
+	if err != nil {
+		return nil, err
+	}
+	p.expr = ast.String()
+	p.ast = ast
+	p.opts = *o
+	return p.withDocumentSelector(o)
+}
+
+// newOptions applies the given options for Parse or Compile.
+func newOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	if o.rfc9535 {
+		return nil, errors.New("the RFC9535 option is not supported")
+	}
+	return o, nil
+}
+
+func parse(path string, o *options) (*AST, error) {
+	l := lex("Path lexer", path)
+	l.opts = o
+	return parseLexemes(func() (lexeme, error) {
+		lx := l.nextLexeme()
+		if lx.typ == lexemeError {
+			return lx, l.err
+		}
+		return lx, nil
+	}, o)
+}
+
+// parseLexemes parses the lexemes returned by next, up to the first lexemeIdentity or lexemeEOF, into an AST.
+func parseLexemes(next func() (lexeme, error), o *options) (*AST, error) {
+	a := &AST{Segments: []Segment{}}
+	for {
+		lx, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		var s Segment
+		switch lx.typ {
+		case lexemeIdentity, lexemeEOF:
+			return a, nil
+
+		case lexemeRoot:
+			s = RootSegment{}
+
+		case lexemeRecursiveDescent:
+			a.Segments = append(a.Segments, RecursiveDescentSegment{})
+			childName := strings.TrimPrefix(lx.val, recursiveDescent)
+			if childName == "" {
+				continue
+			}
+			if strings.HasSuffix(childName, propertyName) {
+				s = propertyNameSegment(strings.TrimSuffix(childName, propertyName))
+				break
+			}
+			s = childSegment(childName)
+
+		case lexemeDotChild:
+			s = childSegment(strings.TrimPrefix(lx.val, dot))
+
+		case lexemeUndottedChild:
+			s = childSegment(lx.val)
+
+		case lexemeBracketChild:
+			s = ChildSegment{Names: bracketChildNames(trimBrackets(lx.val))}
+
+		case lexemeArraySubscript:
+			subscript := strings.TrimSuffix(strings.TrimPrefix(lx.val, leftBracket), rightBracket)
+			if strings.TrimSpace(subscript) == "*" {
+				s = WildcardSegment{}
+				break
+			}
+			s, err = subscriptSegment(subscript)
+			if err != nil {
+				return nil, err
+			}
+
+		case lexemeFilterBegin, lexemeRecursiveFilterBegin:
+			filter, end, err := parseFilterLexemes(next, o)
+			if err != nil {
+				return nil, err
+			}
+			s = FilterSegment{Filter: filter}
+			if strings.TrimSpace(end.val) == filterCloseBracket { // the filter begins a union
+				s, err = parseUnion(next, o, []Segment{s})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		case lexemeUnionBegin:
+			s, err = parseUnion(next, o, []Segment{})
+			if err != nil {
+				return nil, err
+			}
+
+		case lexemePropertyName:
+			s = propertyNameSegment(strings.TrimSuffix(strings.TrimPrefix(lx.val, dot), propertyName))
+
+		case lexemeBracketPropertyName:
+			s = PropertyNameSegment{Names: bracketChildNames(trimBrackets(strings.TrimSuffix(strings.TrimSpace(lx.val), propertyName)))}
+
+		case lexemeArraySubscriptPropertyName:
+			s = PropertyNameSegment{Wildcard: true}
+
+		case lexemeFilterPropertyName:
+			f, ok := previousSegment(a.Segments, len(a.Segments)).(FilterSegment)
+			if !ok {
+				return nil, errors.New("invalid path syntax") // should not happen
+			}
+			a.Segments = a.Segments[:len(a.Segments)-1]
+			s = PropertyNameSegment{Filter: f.Filter}
+
+		case lexemeTagSelector:
+			s = TagSegment{Tag: trimBrackets(lx.val)}
+
+		case lexemeParent:
+			s = ParentSegment{}
+
+		case lexemeAncestors:
+			s = AncestorsSegment{}
+
+		default:
+			return nil, errors.New("invalid path syntax")
+		}
+		a.Segments = append(a.Segments, s)
+	}
+}
+
+// parseFilterLexemes parses the lexemes returned by next, up to the lexemeFilterEnd which matches a lexemeFilterBegin
+// or lexemeRecursiveFilterBegin already returned, into a FilterExpr. It also returns the lexemeFilterEnd.
+func parseFilterLexemes(next func() (lexeme, error), o *options) (*FilterExpr, lexeme, error) {
+	filterLexemes := []lexeme{}
+	filterNestingLevel := 1
+	for {
+		lx, err := next()
+		if err != nil {
+			return nil, lx, err
+		}
+		switch lx.typ {
+		case lexemeFilterBegin:
+			filterNestingLevel++
+		case lexemeFilterEnd:
+			filterNestingLevel--
+			if filterNestingLevel == 0 {
+				tree, err := parseFilter(filterLexemes, o)
+				if err != nil {
+					return nil, lx, err
+				}
+				filter, err := tree.expr(o)
+				return filter, lx, err
+			}
+		case lexemeEOF:
+			// should never happen as lexer should have detected an error
+			return nil, lx, errors.New("missing end of filter")
+		}
+		filterLexemes = append(filterLexemes, lx)
+	}
+}
+
+// parseUnion parses the lexemes returned by next, up to a lexemeUnionEnd, into a UnionSegment with the given
+// initial members.
+func parseUnion(next func() (lexeme, error), o *options, members []Segment) (UnionSegment, error) {
+	for {
+		lx, err := next()
+		if err != nil {
+			return UnionSegment{}, err
+		}
+		switch lx.typ {
+		case lexemeUnionEnd:
+			return UnionSegment{Members: members}, nil
+
+		case lexemeUnionSeparator:
+
+		case lexemeUnionName:
+			members = append(members, ChildSegment{Names: bracketChildNames(strings.TrimSpace(lx.val))})
+
+		case lexemeUnionSubscript:
+			if strings.TrimSpace(lx.val) == "*" {
+				members = append(members, WildcardSegment{})
+				break
+			}
+			s, err := subscriptSegment(lx.val)
+			if err != nil {
+				return UnionSegment{}, err
+			}
+			members = append(members, s)
+
+		case lexemeFilterBegin:
+			filter, _, err := parseFilterLexemes(next, o)
+			if err != nil {
+				return UnionSegment{}, err
+			}
+			members = append(members, FilterSegment{Filter: filter})
+
+		default:
+			// should never happen as lexer should have detected an error
+			return UnionSegment{}, errors.New("invalid union syntax")
+		}
+	}
+}
+
+// childSegment returns the segment for a dotted or undotted child name, which is a wildcard if it is `*`.
+func childSegment(childName string) Segment {
+	if childName == "*" {
+		return WildcardSegment{}
+	}
+	return ChildSegment{Names: []string{unescape(childName)}}
+}
+
+// propertyNameSegment returns the segment for the property name of a dotted or undotted child, which is a wildcard
+// if the child name is `*`.
+func propertyNameSegment(childName string) Segment {
+	if childName == "*" {
+		return PropertyNameSegment{Wildcard: true}
+	}
+	return PropertyNameSegment{Names: []string{unescape(childName)}}
+}
+
+// trimBrackets returns the content of a bracket child lexeme.
+func trimBrackets(val string) string {
+	val = strings.TrimSpace(val)
+	val = strings.TrimSuffix(strings.TrimPrefix(val, leftBracket), rightBracket)
+	return strings.TrimSpace(val)
+}
+
+// subscriptSegment parses the content of an array subscript lexeme other than `*`.
+func subscriptSegment(subscript string) (SubscriptSegment, error) {
+	subscripts, err := parseSubscripts(subscript)
+	if err != nil {
+		return SubscriptSegment{}, err
+	}
+	return SubscriptSegment{Subscripts: subscripts}, nil
+}
+
+// expr converts a checked filter parse tree into a FilterExpr.
+func (n *filterNode) expr(o *options) (*FilterExpr, error) {
+	if n == nil {
+		return nil, nil
+	}
+	f := &FilterExpr{}
+	switch n.lexeme.typ {
+	case lexemeFilterOr:
+		f.Kind = FilterOr
+	case lexemeFilterAnd:
+		f.Kind = FilterAnd
+	case lexemeFilterNot:
+		f.Kind = FilterNot
+	case lexemeFilterEquality, lexemeFilterInequality,
+		lexemeFilterGreaterThan, lexemeFilterGreaterThanOrEqual,
+		lexemeFilterLessThan, lexemeFilterLessThanOrEqual,
+		lexemeFilterMatchesRegularExpression:
+		f.Kind = FilterComparison
+		f.Operator = comparisonOperators[n.lexeme.typ]
+	case lexemeFilterAdd, lexemeFilterSubtract, lexemeFilterMultiply, lexemeFilterDivide, lexemeFilterModulo:
+		f.Kind = FilterArithmetic
+		f.Operator = strings.TrimSpace(n.lexeme.val)
+	case lexemeFilterUnaryMinus:
+		f.Kind = FilterNegation
+	case lexemeFilterKey:
+		f.Kind = FilterKey
+	case lexemeFilterFunctionName:
+		f.Kind = FilterFunction
+		f.Name = strings.TrimSpace(n.lexeme.val)
+	case lexemeFilterAt, lexemeRoot:
+		f.Kind = FilterCurrent
+		if n.lexeme.typ == lexemeRoot {
+			f.Kind = FilterRoot
+		}
+		i := 0
+		path, err := parseLexemes(func() (lexeme, error) {
+			if i >= len(n.subpath) {
+				return lexeme{typ: lexemeEOF}, nil
+			}
+			i++
+			return n.subpath[i-1], nil
+		}, o)
+		if err != nil {
+			return nil, err
+		}
+		f.Path = path
+	default:
+		kind, ok := literalKinds[n.lexeme.typ]
+		if !ok {
+			return nil, fmt.Errorf("invalid filter lexeme %q", n.lexeme.val) // should not happen
+		}
+		f.Kind = kind
+		f.Value = strings.TrimSpace(n.lexeme.val)
+	}
+
+	for _, c := range n.children {
+		o, err := c.expr(o)
+		if err != nil {
+			return nil, err
+		}
+		f.Operands = append(f.Operands, o)
+	}
+	return f, nil
+}
+
+var comparisonOperators = map[lexemeType]string{
+	lexemeFilterEquality:                 filterEquality,
+	lexemeFilterInequality:               filterInequality,
+	lexemeFilterGreaterThan:              ">",
+	lexemeFilterGreaterThanOrEqual:       ">=",
+	lexemeFilterLessThan:                 "<",
+	lexemeFilterLessThanOrEqual:          "<=",
+	lexemeFilterMatchesRegularExpression: filterMatchesRegularExpression,
+}
+
+var literalKinds = map[lexemeType]FilterKind{
+	lexemeFilterIntegerLiteral:           FilterInteger,
+	lexemeFilterFloatLiteral:             FilterFloat,
+	lexemeFilterStringLiteral:            FilterString,
+	lexemeFilterBooleanLiteral:           FilterBoolean,
+	lexemeFilterNullLiteral:              FilterNull,
+	lexemeFilterRegularExpressionLiteral: FilterRegexp,
+}
+
+// node converts a FilterExpr into a filter parse tree, which the caller must check.
+func (f *FilterExpr) node(o *options) (*filterNode, error) {
+	if f == nil {
+		return nil, nil
+	}
+	n := &filterNode{
+		subpath:  []lexeme{},
+		children: []*filterNode{},
+	}
+	operands := 0
+	switch f.Kind {
+	case FilterOr:
+		n.lexeme = lexeme{typ: lexemeFilterOr, val: filterDisjunction}
+		operands = 2
+	case FilterAnd:
+		n.lexeme = lexeme{typ: lexemeFilterAnd, val: filterConjunction}
+		operands = 2
+	case FilterNot:
+		n.lexeme = lexeme{typ: lexemeFilterNot, val: filterNot}
+		operands = 1
+	case FilterComparison:
+		for typ, op := range comparisonOperators {
+			if op == f.Operator {
+				n.lexeme = lexeme{typ: typ, val: op}
+			}
+		}
+		if n.lexeme.val == "" {
+			return nil, fmt.Errorf("invalid comparison operator %q", f.Operator)
+		}
+		if f.Operator == filterMatchesRegularExpression && len(f.Operands) > 0 && f.Operands[0] != nil {
+			switch f.Operands[0].Kind {
+			case FilterString, FilterInteger, FilterFloat:
+				return nil, fmt.Errorf("literal cannot be matched using %s", filterMatchesRegularExpression)
+			}
+		}
+		operands = 2
+	case FilterArithmetic:
+		typ, ok := arithmeticOperatorLexeme[f.Operator]
+		if !ok {
+			return nil, fmt.Errorf("invalid arithmetic operator %q", f.Operator)
+		}
+		n.lexeme = lexeme{typ: typ, val: f.Operator}
+		operands = 2
+	case FilterNegation:
+		n.lexeme = lexeme{typ: lexemeFilterUnaryMinus, val: filterSubtract}
+		operands = 1
+	case FilterKey:
+		n.lexeme = lexeme{typ: lexemeFilterKey, val: filterKey}
+	case FilterFunction:
+		n.lexeme = lexeme{typ: lexemeFilterFunctionName, val: f.Name}
+		operands = len(f.Operands)
+	case FilterCurrent, FilterRoot:
+		n.lexeme = lexeme{typ: lexemeFilterAt, val: filterAt}
+		if f.Kind == FilterRoot {
+			n.lexeme = lexeme{typ: lexemeRoot, val: root}
+		}
+		if f.Path == nil {
+			return nil, fmt.Errorf("%s has a nil path", f.Kind)
+		}
+		if len(f.Path.Segments) > 0 {
+			if _, ok := f.Path.Segments[0].(RootSegment); ok {
+				return nil, fmt.Errorf("%s path must not begin with a root segment", f.Kind)
+			}
+		}
+		subpath, err := subpathLexemes(f.Path, o)
+		if err != nil {
+			return nil, err
+		}
+		n.subpath = subpath
+	default:
+		for typ, kind := range literalKinds {
+			if kind == f.Kind {
+				n.lexeme = lexeme{typ: typ, val: f.Value}
+			}
+		}
+		if n.lexeme.val == "" || !isLiteral(n.lexeme.typ, f.Value) {
+			return nil, fmt.Errorf("invalid %s literal %q", f.Kind, f.Value)
+		}
+	}
+
+	if len(f.Operands) != operands {
+		return nil, fmt.Errorf("%s has %d operand(s) but requires %d", f.Kind, len(f.Operands), operands)
+	}
+	for i, operand := range f.Operands {
+		if operand != nil && (operand.Kind == FilterRegexp) != (f.Kind == FilterComparison && f.Operator == filterMatchesRegularExpression && i == 1) {
+			return nil, fmt.Errorf("%s literal must be the right operand of %s", FilterRegexp, filterMatchesRegularExpression)
+		}
+		c, err := operand.node(o)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, fmt.Errorf("%s has a nil operand", f.Kind)
+		}
+		n.children = append(n.children, c)
+	}
+	return n, nil
+}
+
+// isLiteral reports whether the given value consists of exactly one valid literal of the given lexeme type.
+func isLiteral(typ lexemeType, val string) bool {
+	l := lex("Literal lexer", val)
+	switch typ {
+	case lexemeFilterIntegerLiteral, lexemeFilterFloatLiteral:
+		lexNumericLiteral(l, nil)
+	case lexemeFilterStringLiteral:
+		lexStringLiteral(l, nil)
+	case lexemeFilterBooleanLiteral:
+		lexBooleanLiteral(l, nil)
+	case lexemeFilterNullLiteral:
+		lexNullLiteral(l, nil)
+	case lexemeFilterRegularExpressionLiteral:
+		lexRegularExpressionLiteral(l, nil)
+	}
+	select {
+	case lx := <-l.items:
+		return lx.typ == typ && l.empty()
+	default:
+		return false
+	}
+}
+
+// subpathLexemes returns the lexemes of the path following `@` or `$` in a filter.
+func subpathLexemes(a *AST, o *options) ([]lexeme, error) {
+	path := a.String()
+	l := lex("Path lexer", path)
+	l.opts = o
+	lexemes := []lexeme{}
+	for {
+		lx := l.nextLexeme()
+		switch lx.typ {
+		case lexemeError:
+			return nil, l.err
+		case lexemeIdentity:
+			continue
+		case lexemeEOF:
+			return lexemes, nil
+		case lexemeRoot:
+			if !strings.HasPrefix(path, root) {
+				continue // implicit root
+			}
+		}
+		lexemes = append(lexemes, lx)
+	}
+}
+
+// compileAST constructs a Path from an AST.
+func compileAST(a *AST, o *options) (*Path, error) {
+	if a == nil {
+		return new(identity), nil
+	}
+	return compileSegments(a.Segments, new(identity), o)
+}
+
+// compileSegments constructs a Path which applies the given segments followed by p.
+func compileSegments(segments []Segment, p *Path, o *options) (*Path, error) {
+	for i := len(segments) - 1; i >= 0; i-- {
+		subPath := p
+		switch s := segments[i].(type) {
+		case RootSegment:
+			if i > 0 {
+				return nil, errors.New("root segment must be the first segment")
+			}
+			p = new(func(loc *location, root *yaml.Node) locationIterator {
+				if loc.node.Kind == yaml.DocumentNode {
+					loc = loc.child(0)
+				}
+				return compose(fromLocations(loc), subPath, root)
+			})
+
+		case RecursiveDescentSegment:
+			switch segmentAfter(segments, i).(type) {
+			case ChildSegment, WildcardSegment, SubscriptSegment, FilterSegment, UnionSegment, PropertyNameSegment, TagSegment:
+			default:
+				return nil, errors.New("recursive descent must be followed by a child name, array access or filter")
+			}
+			p = new(func(loc *location, root *yaml.Node) locationIterator {
+				return compose(fromLocations(loc).recurse(), subPath, root)
+			})
+
+		case ChildSegment:
+			if len(s.Names) == 0 {
+				return nil, errors.New("child segment has no names")
+			}
+			p = childrenThen(s.Names, subPath)
+
+		case WildcardSegment:
+			p = allChildrenThen(subPath)
+
+		case SubscriptSegment:
+			if len(s.Subscripts) == 0 {
+				return nil, errors.New("subscript segment has no subscripts")
+			}
+			p = arraySubscriptThen(s.Subscripts, subPath)
+
+		case FilterSegment:
+			if s.Filter == nil {
+				return nil, errors.New("filter segment has no filter")
+			}
+			tree, err := s.Filter.node(o)
+			if err != nil {
+				return nil, err
+			}
+			if err := tree.checkLogical(o); err != nil {
+				return nil, err
+			}
+			if _, recursive := previousSegment(segments, i).(RecursiveDescentSegment); recursive {
+				p = recursiveFilterThen(tree, subPath)
+			} else {
+				p = filterThen(tree, subPath)
+			}
+
+		case UnionSegment:
+			if len(s.Members) == 0 {
+				return nil, errors.New("union has no members")
+			}
+			members := []*Path{}
+			for _, m := range s.Members {
+				switch m.(type) {
+				case ChildSegment, WildcardSegment, SubscriptSegment, FilterSegment:
+				default:
+					return nil, fmt.Errorf("invalid union member %T", m)
+				}
+				member, err := compileSegments([]Segment{m}, subPath, o)
+				if err != nil {
+					return nil, err
+				}
+				members = append(members, member)
+			}
+			p = unionThen(members)
+
+		case PropertyNameSegment:
+			switch {
+			case s.Filter != nil:
+				tree, err := s.Filter.node(o)
+				if err != nil {
+					return nil, err
+				}
+				if err := tree.checkLogical(o); err != nil {
+					return nil, err
+				}
+				if _, recursive := previousSegment(segments, i).(RecursiveDescentSegment); recursive {
+					p = recursiveFilterThen(tree, keyThen(subPath))
+				} else {
+					p = entryFilterThen(tree, keyThen(subPath))
+				}
+			case s.Wildcard:
+				p = propertyNameArraySubscriptThen("*", subPath)
+			case len(s.Names) == 0:
+				return nil, errors.New("property name segment has no names")
+			default:
+				p = propertyNamesThen(s.Names, subPath)
+			}
+
+		case TagSegment:
+			if !strings.HasPrefix(s.Tag, "!") || strings.ContainsAny(s.Tag, rightBracket) {
+				return nil, fmt.Errorf("invalid tag %q", s.Tag)
+			}
+			p = tagThen(s.Tag, subPath)
+
+		case ParentSegment:
+			p = parentThen(subPath)
+
+		case AncestorsSegment:
+			p = ancestorsThen(subPath)
+
+		default:
+			return nil, fmt.Errorf("invalid segment %T", s)
+		}
+	}
+	return p, nil
+}
+
+// previousSegment returns the segment preceding the segment at the given index, or nil if there is none.
+func previousSegment(segments []Segment, i int) Segment {
+	if i == 0 {
+		return nil
+	}
+	return segments[i-1]
+}
+
+// segmentAfter returns the segment following the segment at the given index, or nil if there is none.
+func segmentAfter(segments []Segment, i int) Segment {
+	if i == len(segments)-1 {
+		return nil
+	}
+	return segments[i+1]
+}
@@ -0,0 +1,663 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name           string
+		path           string
+		expectedString string
+		expectedErr    string
+		focus          bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:           "identity",
+			path:           "",
+			expectedString: "",
+		},
+		{
+			name:           "root",
+			path:           "$",
+			expectedString: "$",
+		},
+		{
+			name:           "implicit root",
+			path:           "a.b",
+			expectedString: "$['a']['b']",
+		},
+		{
+			name:           "dot and bracket children",
+			path:           `$.a["b", 'c d']`,
+			expectedString: "$['a']['b','c d']",
+		},
+		{
+			name:           "escaped child names",
+			path:           `$['it\'s','back\\slash']`,
+			expectedString: `$['it\'s','back\\slash']`,
+		},
+		{
+			name:           "quoted wildcard is a child name",
+			path:           "$['*']",
+			expectedString: "$['*']",
+		},
+		{
+			name:           "wildcards",
+			path:           "$.*[*]",
+			expectedString: "$[*][*]",
+		},
+		{
+			name:           "subscripts",
+			path:           "$[ 0 ][1:3][::-1][-1:][0, 2:]",
+			expectedString: "$[0][1:3][::-1][-1:][0,2:]",
+		},
+		{
+			name:           "recursive descent",
+			path:           "$..a..*..[0]",
+			expectedString: "$..['a']..[*]..[0]",
+		},
+		{
+			name:           "property names",
+			path:           "$.a.b~",
+			expectedString: "$['a']['b']~",
+		},
+		{
+			name:           "bracket property names",
+			path:           "$['a','b']~",
+			expectedString: "$['a','b']~",
+		},
+		{
+			name:           "wildcard property names",
+			path:           "$.a[*]~",
+			expectedString: "$['a'][*]~",
+		},
+		{
+			name:           "filter",
+			path:           "$.a[?(@.b=='x'&&@.c>1)]",
+			expectedString: "$['a'][?(@['b'] == 'x' && @['c'] > 1)]",
+		},
+		{
+			name:           "filter with redundant brackets",
+			path:           "$[?((@.a || @.b) || (!(@.c) && ($.d)))]",
+			expectedString: "$[?(@['a'] || @['b'] || !@['c'] && $['d'])]",
+		},
+		{
+			name:           "filter with necessary brackets",
+			path:           "$[?((@.a || @.b) && !(@.c && @.d) || (@.e || @.f))]",
+			expectedString: "$[?((@['a'] || @['b']) && !(@['c'] && @['d']) || (@['e'] || @['f']))]",
+		},
+		{
+			name:           "filter literals",
+			path:           `$[?(@.a == "x" || @.b == 1.5 || @.c == true || @.d == null || @.e =~ /a\/b/)]`,
+			expectedString: `$[?(@['a'] == "x" || @['b'] == 1.5 || @['c'] == true || @['d'] == null || @['e'] =~ /a\/b/)]`,
+		},
+		{
+			name:           "filter with function",
+			path:           "$[?(length(@.a)>1&&match(@.b,'x.*'))]",
+			expectedString: "$[?(length(@['a']) > 1 && match(@['b'], 'x.*'))]",
+		},
+		{
+			name:           "nested filter",
+			path:           "$[?(@.a[?(@.b)])]",
+			expectedString: "$[?(@['a'][?(@['b'])])]",
+		},
+		{
+			name:           "recursive filter",
+			path:           "$..[?(@.a)]",
+			expectedString: "$..[?(@['a'])]",
+		},
+		{
+			name:           "tags",
+			path:           "$..[!Ref].a[!!str]",
+			expectedString: "$..[!Ref]['a'][!!str]",
+		},
+		{
+			name:           "tag in filter",
+			path:           "$[?(@.a[!GetAtt])]",
+			expectedString: "$[?(@['a'][!GetAtt])]",
+		},
+		{
+			name:           "property names followed by segments",
+			path:           "$..a~^.*~[?(@ != 'b')]",
+			expectedString: "$..['a']~^[*]~[?(@ != 'b')]",
+		},
+		{
+			name:           "key filter",
+			path:           "$.a[?(@~ =~ /^b/ && @~ != $.c)]~",
+			expectedString: "$['a'][?(@~ =~ /^b/ && @~ != $['c'])]~",
+		},
+		{
+			name:           "parent and ancestors",
+			path:           "$.a^.b^*[?(@^.c)]",
+			expectedString: "$['a']^['b']^*[?(@^['c'])]",
+		},
+		{
+			name:           "filter with arithmetic",
+			path:           "$[?(@.a * (@.b + 1) - -@.c % 2 > $.d / 2 + length(@.e))]",
+			expectedString: "$[?(@['a'] * (@['b'] + 1) - -@['c'] % 2 > $['d'] / 2 + length(@['e']))]",
+		},
+		{
+			name:           "filter with arithmetic brackets",
+			path:           "$[?((@.a - 1) - (@.b - 2) == -(1) && ((@.c)) * -(@.d * 2) < 0)]",
+			expectedString: "$[?(@['a'] - 1 - (@['b'] - 2) == -(1) && @['c'] * -(@['d'] * 2) < 0)]",
+		},
+		{
+			name:           "unions",
+			path:           `$[0, 'a', 2:4, *, ?(@.b)][?(@.c),"d"]`,
+			expectedString: "$[0,'a',2:4,*,?(@['b'])][?(@['c']),'d']",
+		},
+		{
+			name:        "syntax error",
+			path:        "$.a[",
+			expectedErr: `unmatched [ at position 4, following ".a["`,
+		},
+		{
+			name:        "invalid filter",
+			path:        "$[?(length(@.a))]",
+			expectedErr: "result of function length() must be compared",
+		},
+		{
+			name:        "arithmetic expression not compared",
+			path:        "$[?(@.a + 1)]",
+			expectedErr: "result of arithmetic expression must be compared",
+		},
+		{
+			name:        "logical expression in arithmetic expression",
+			path:        "$[?((@.a > 1) + 1 == 2)]",
+			expectedErr: "result of logical expression cannot be compared",
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			ast, err := yamlpath.Parse(tc.path)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedString, ast.String())
+
+			// the canonical form parses to the same AST
+			reparsed, err := yamlpath.Parse(ast.String())
+			require.NoError(t, err)
+			require.Equal(t, ast, reparsed)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestParseAST(t *testing.T) {
+	ast, err := yamlpath.Parse("$.a[0,1:][?(@.b > 1)]")
+	require.NoError(t, err)
+
+	one := 1
+	require.Equal(t, &yamlpath.AST{
+		Segments: []yamlpath.Segment{
+			yamlpath.RootSegment{},
+			yamlpath.ChildSegment{Names: []string{"a"}},
+			yamlpath.SubscriptSegment{Subscripts: []yamlpath.Subscript{
+				{Index: 0},
+				{Slice: &yamlpath.Slice{Start: &one}},
+			}},
+			yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+				Kind:     yamlpath.FilterComparison,
+				Operator: ">",
+				Operands: []*yamlpath.FilterExpr{
+					{
+						Kind: yamlpath.FilterCurrent,
+						Path: &yamlpath.AST{Segments: []yamlpath.Segment{yamlpath.ChildSegment{Names: []string{"b"}}}},
+					},
+					{
+						Kind:  yamlpath.FilterInteger,
+						Value: "1",
+					},
+				},
+			}},
+		},
+	}, ast)
+}
+
+func TestCompile(t *testing.T) {
+	input := `a:
+  b: [1, 2, 3, 4]
+  c: x
+  '*': y
+`
+	var n yaml.Node
+	err := yaml.Unmarshal([]byte(input), &n)
+	require.NoError(t, err)
+
+	two := 2
+	minusOne := -1
+	current := &yamlpath.FilterExpr{Kind: yamlpath.FilterCurrent, Path: &yamlpath.AST{}}
+	// filter returns an AST which filters the children of b using the given expression.
+	filter := func(f *yamlpath.FilterExpr) *yamlpath.AST {
+		return &yamlpath.AST{Segments: []yamlpath.Segment{
+			yamlpath.RootSegment{},
+			yamlpath.ChildSegment{Names: []string{"a"}},
+			yamlpath.ChildSegment{Names: []string{"b"}},
+			yamlpath.FilterSegment{Filter: f},
+		}}
+	}
+	comparison := func(operator string, left, right *yamlpath.FilterExpr) *yamlpath.AST {
+		return filter(&yamlpath.FilterExpr{
+			Kind:     yamlpath.FilterComparison,
+			Operator: operator,
+			Operands: []*yamlpath.FilterExpr{left, right},
+		})
+	}
+	cases := []struct {
+		name        string
+		ast         *yamlpath.AST
+		expected    []string
+		expectedErr string
+		focus       bool // if true, run only tests with focus set to true
+	}{
+		{
+			name: "children",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.ChildSegment{Names: []string{"c", "*"}},
+			}},
+			expected: []string{"x", "y"},
+		},
+		{
+			name: "slice",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.SubscriptSegment{Subscripts: []yamlpath.Subscript{
+					{Index: 0},
+					{Slice: &yamlpath.Slice{Start: &two}},
+				}},
+			}},
+			expected: []string{"1", "3", "4"},
+		},
+		{
+			name: "filter",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.RecursiveDescentSegment{},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+					Kind:     yamlpath.FilterComparison,
+					Operator: ">=",
+					Operands: []*yamlpath.FilterExpr{
+						{Kind: yamlpath.FilterCurrent, Path: &yamlpath.AST{}},
+						{Kind: yamlpath.FilterInteger, Value: "3"},
+					},
+				}},
+			}},
+			expected: []string{"3", "4"},
+		},
+		{
+			name: "property names",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.PropertyNameSegment{Wildcard: true},
+			}},
+			expected: []string{"b", "c", "*"},
+		},
+		{
+			name: "parent",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.ParentSegment{},
+				yamlpath.ChildSegment{Names: []string{"c"}},
+			}},
+			expected: []string{"x"},
+		},
+		{
+			name: "property names with filter",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.PropertyNameSegment{Filter: &yamlpath.FilterExpr{
+					Kind:     yamlpath.FilterComparison,
+					Operator: "!=",
+					Operands: []*yamlpath.FilterExpr{
+						{Kind: yamlpath.FilterKey},
+						{Kind: yamlpath.FilterString, Value: "'b'"},
+					},
+				}},
+			}},
+			expected: []string{"c", "*"},
+		},
+		{
+			name: "property name followed by segments",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.PropertyNameSegment{Names: []string{"b"}},
+				yamlpath.ParentSegment{},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.SubscriptSegment{Subscripts: []yamlpath.Subscript{{Index: minusOne}}},
+			}},
+			expected: []string{"4"},
+		},
+		{
+			name: "slice with zero step",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.SubscriptSegment{Subscripts: []yamlpath.Subscript{
+					{Slice: &yamlpath.Slice{Step: new(int)}},
+				}},
+			}},
+			expected: []string{},
+		},
+		{
+			name: "union",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.UnionSegment{Members: []yamlpath.Segment{
+					yamlpath.SubscriptSegment{Subscripts: []yamlpath.Subscript{{Index: 1}}},
+					yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+						Kind:     yamlpath.FilterComparison,
+						Operator: ">",
+						Operands: []*yamlpath.FilterExpr{
+							{Kind: yamlpath.FilterCurrent, Path: &yamlpath.AST{Segments: []yamlpath.Segment{}}},
+							{Kind: yamlpath.FilterInteger, Value: "2"},
+						},
+					}},
+				}},
+			}},
+			expected: []string{"2", "3", "4"},
+		},
+		{
+			name: "invalid union member",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.UnionSegment{Members: []yamlpath.Segment{yamlpath.RootSegment{}}},
+			}},
+			expectedErr: "invalid union member yamlpath.RootSegment",
+		},
+		{
+			name: "invalid filter",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+					Kind:     yamlpath.FilterComparison,
+					Operator: "<>",
+					Operands: []*yamlpath.FilterExpr{
+						{Kind: yamlpath.FilterCurrent},
+						{Kind: yamlpath.FilterInteger, Value: "1"},
+					},
+				}},
+			}},
+			expectedErr: `invalid comparison operator "<>"`,
+		},
+		{
+			name: "arithmetic",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.ChildSegment{Names: []string{"b"}},
+				yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+					Kind:     yamlpath.FilterComparison,
+					Operator: "==",
+					Operands: []*yamlpath.FilterExpr{
+						{
+							Kind:     yamlpath.FilterArithmetic,
+							Operator: "%",
+							Operands: []*yamlpath.FilterExpr{
+								{Kind: yamlpath.FilterCurrent, Path: &yamlpath.AST{}},
+								{Kind: yamlpath.FilterInteger, Value: "2"},
+							},
+						},
+						{
+							Kind:     yamlpath.FilterNegation,
+							Operands: []*yamlpath.FilterExpr{{Kind: yamlpath.FilterInteger, Value: "0"}},
+						},
+					},
+				}},
+			}},
+			expected: []string{"2", "4"},
+		},
+		{
+			name: "invalid arithmetic operator",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+					Kind:     yamlpath.FilterComparison,
+					Operator: "==",
+					Operands: []*yamlpath.FilterExpr{
+						{
+							Kind:     yamlpath.FilterArithmetic,
+							Operator: "^",
+							Operands: []*yamlpath.FilterExpr{
+								{Kind: yamlpath.FilterCurrent},
+								{Kind: yamlpath.FilterInteger, Value: "2"},
+							},
+						},
+						{Kind: yamlpath.FilterInteger, Value: "0"},
+					},
+				}},
+			}},
+			expectedErr: `invalid arithmetic operator "^"`,
+		},
+		{
+			name: "missing operand",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.FilterSegment{Filter: &yamlpath.FilterExpr{
+					Kind:     yamlpath.FilterAnd,
+					Operands: []*yamlpath.FilterExpr{{Kind: yamlpath.FilterCurrent}},
+				}},
+			}},
+			expectedErr: "FilterAnd has 1 operand(s) but requires 2",
+		},
+		{
+			name: "invalid tag",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.TagSegment{Tag: "Ref"},
+			}},
+			expectedErr: `invalid tag "Ref"`,
+		},
+		{
+			name:     "negative integer literal",
+			ast:      comparison(">", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterInteger, Value: "-2"}),
+			expected: []string{"1", "2", "3", "4"},
+		},
+		{
+			name:        "invalid integer literal",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterInteger, Value: "abc"}),
+			expectedErr: `invalid FilterInteger literal "abc"`,
+		},
+		{
+			name:        "integer literal with fraction",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterInteger, Value: "1.5"}),
+			expectedErr: `invalid FilterInteger literal "1.5"`,
+		},
+		{
+			name:        "invalid float literal",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterFloat, Value: "1.2.3"}),
+			expectedErr: `invalid FilterFloat literal "1.2.3"`,
+		},
+		{
+			name:        "invalid boolean literal",
+			ast:         filter(&yamlpath.FilterExpr{Kind: yamlpath.FilterBoolean, Value: "yes"}),
+			expectedErr: `invalid FilterBoolean literal "yes"`,
+		},
+		{
+			name:        "invalid null literal",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterNull, Value: "nil"}),
+			expectedErr: `invalid FilterNull literal "nil"`,
+		},
+		{
+			name:        "unquoted string literal",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterString, Value: "x"}),
+			expectedErr: `invalid FilterString literal "x"`,
+		},
+		{
+			name:        "string literal with trailing characters",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterString, Value: "'x'y"}),
+			expectedErr: `invalid FilterString literal "'x'y"`,
+		},
+		{
+			name:        "invalid regular expression",
+			ast:         comparison("=~", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterRegexp, Value: "/(/"}),
+			expectedErr: `invalid FilterRegexp literal "/(/"`,
+		},
+		{
+			name:        "undelimited regular expression",
+			ast:         comparison("=~", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterRegexp, Value: "x"}),
+			expectedErr: `invalid FilterRegexp literal "x"`,
+		},
+		{
+			name:        "match against string literal",
+			ast:         comparison("=~", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterString, Value: "'x'"}),
+			expectedErr: "FilterRegexp literal must be the right operand of =~",
+		},
+		{
+			name:        "regular expression compared for equality",
+			ast:         comparison("==", current, &yamlpath.FilterExpr{Kind: yamlpath.FilterRegexp, Value: "/x/"}),
+			expectedErr: "FilterRegexp literal must be the right operand of =~",
+		},
+		{
+			name:        "match literal",
+			ast:         comparison("=~", &yamlpath.FilterExpr{Kind: yamlpath.FilterString, Value: "'x'"}, &yamlpath.FilterExpr{Kind: yamlpath.FilterRegexp, Value: "/x/"}),
+			expectedErr: "literal cannot be matched using =~",
+		},
+		{
+			name:        "current node with nil path",
+			ast:         comparison("==", &yamlpath.FilterExpr{Kind: yamlpath.FilterCurrent}, &yamlpath.FilterExpr{Kind: yamlpath.FilterInteger, Value: "1"}),
+			expectedErr: "FilterCurrent has a nil path",
+		},
+		{
+			name:        "root node with nil path",
+			ast:         filter(&yamlpath.FilterExpr{Kind: yamlpath.FilterRoot}),
+			expectedErr: "FilterRoot has a nil path",
+		},
+		{
+			name: "current node path beginning with root",
+			ast: filter(&yamlpath.FilterExpr{
+				Kind: yamlpath.FilterCurrent,
+				Path: &yamlpath.AST{Segments: []yamlpath.Segment{yamlpath.RootSegment{}}},
+			}),
+			expectedErr: "FilterCurrent path must not begin with a root segment",
+		},
+		{
+			name:        "missing filter",
+			ast:         filter(nil),
+			expectedErr: "filter segment has no filter",
+		},
+		{
+			name: "trailing recursive descent",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.RecursiveDescentSegment{},
+			}},
+			expectedErr: "recursive descent must be followed by a child name, array access or filter",
+		},
+		{
+			name: "recursive descent followed by parent",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.RootSegment{},
+				yamlpath.RecursiveDescentSegment{},
+				yamlpath.ParentSegment{},
+			}},
+			expectedErr: "recursive descent must be followed by a child name, array access or filter",
+		},
+		{
+			name: "root segment not first",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.ChildSegment{Names: []string{"a"}},
+				yamlpath.RootSegment{},
+			}},
+			expectedErr: "root segment must be the first segment",
+		},
+		{
+			name: "child segment without names",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.ChildSegment{},
+			}},
+			expectedErr: "child segment has no names",
+		},
+		{
+			name: "subscript segment without subscripts",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.SubscriptSegment{},
+			}},
+			expectedErr: "subscript segment has no subscripts",
+		},
+		{
+			name: "property name segment without names",
+			ast: &yamlpath.AST{Segments: []yamlpath.Segment{
+				yamlpath.PropertyNameSegment{},
+			}},
+			expectedErr: "property name segment has no names",
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.Compile(tc.ast)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			actual, err := p.Find(&n)
+			require.NoError(t, err)
+			values := []string{}
+			for _, a := range actual {
+				values = append(values, a.Value)
+			}
+			require.Equal(t, tc.expected, values)
+
+			// compiling the canonical form gives an equivalent path
+			q, err := yamlpath.NewPath(tc.ast.String())
+			require.NoError(t, err)
+			expected, err := q.Find(&n)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
@@ -6,7 +6,11 @@
 
 package yamlpath
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 type comparison int
 
@@ -56,11 +60,16 @@ func lessThanOrEqual(c comparison) bool {
 	return c == compareLessThan || c == compareEqual
 }
 
+// compareStrings compares two strings by code point, as required by RFC 9535. Comparing the UTF-8 encodings of the
+// strings byte by byte gives the same result.
 func compareStrings(a, b string) comparison {
-	if a == b {
-		return compareEqual
+	if a < b {
+		return compareLessThan
 	}
-	return compareIncomparable
+	if a > b {
+		return compareGreaterThan
+	}
+	return compareEqual
 }
 
 func compareFloat64(lhs, rhs float64) comparison {
@@ -73,18 +82,84 @@ func compareFloat64(lhs, rhs float64) comparison {
 	return compareEqual
 }
 
-// compareNodeValues compares two values each of which may be a string, integer, or float
+func compareTimes(lhs, rhs time.Time) comparison {
+	if lhs.Before(rhs) {
+		return compareLessThan
+	}
+	if lhs.After(rhs) {
+		return compareGreaterThan
+	}
+	return compareEqual
+}
+
+// compareNodeValues compares two values each of which may be a string, integer, float, or timestamp. Strings and
+// timestamps are compared byte for byte and ordered by code point, but are not ordered with respect to numbers.
 func compareNodeValues(lhs, rhs typedValue) comparison {
 	if lhs.typ.isNumeric() && rhs.typ.isNumeric() {
 		return compareFloat64(mustParseFloat64(lhs.val), mustParseFloat64(rhs.val))
 	}
-	if (lhs.typ != stringValueType && !lhs.typ.isNumeric()) || (rhs.typ != stringValueType && !rhs.typ.isNumeric()) {
+	if (!lhs.typ.isTemporal() && !lhs.typ.isNumeric()) || (!rhs.typ.isTemporal() && !rhs.typ.isNumeric()) {
 		// we cannot compare values
 		return compareIncomparable
 	}
+	if lhs.typ.isNumeric() != rhs.typ.isNumeric() {
+		// a number and a string may be equal but are not ordered
+		if lhs.val == rhs.val {
+			return compareEqual
+		}
+		return compareIncomparable
+	}
 	return compareStrings(lhs.val, rhs.val)
 }
 
+// compareTemporalValues compares two values as points in time, if both are timestamps or strings which are timestamps,
+// such as '2024-01-01T00:00:00Z', or as lengths of time, if both are strings which are durations, such as '90s' and
+// '5m'. Other values are incomparable.
+func compareTemporalValues(lhs, rhs typedValue) comparison {
+	if l, ok := lhs.timestamp(); ok {
+		if r, ok := rhs.timestamp(); ok {
+			return compareTimes(l, r)
+		}
+	}
+	if l, ok := lhs.duration(); ok {
+		if r, ok := rhs.duration(); ok {
+			return compareFloat64(float64(l), float64(r))
+		}
+	}
+	return compareIncomparable
+}
+
+// timestampFormats are the formats of YAML timestamps, see https://yaml.org/type/timestamp.html.
+var timestampFormats = []string{
+	"2006-1-2T15:4:5.999999999Z07:00",
+	"2006-1-2t15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999",
+	"2006-1-2",
+}
+
+func parseTimestamp(s string) (time.Time, bool) {
+	for _, format := range timestampFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// durationUnits are the final letters of the units accepted by time.ParseDuration.
+const durationUnits = "shm"
+
+// parseDuration parses a duration such as 1h30m. Unlike time.ParseDuration, it requires a unit, so that strings such
+// as "0" are not durations.
+func parseDuration(s string) (time.Duration, bool) {
+	if s == "" || !strings.ContainsRune(durationUnits, rune(s[len(s)-1])) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
 func mustParseFloat64(s string) float64 {
 	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
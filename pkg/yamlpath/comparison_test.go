@@ -96,6 +96,26 @@ func TestComparators(t *testing.T) {
 				compareStrings("a", "b"): true,
 			},
 		},
+		{
+			name:       "string less than",
+			comparator: lessThan,
+			comparisons: map[comparison]bool{
+				compareStrings("a", "b"):               true,
+				compareStrings("b", "a"):               false,
+				compareStrings("B", "a"):               true,
+				compareStrings("a", "ab"):              true,
+				compareStrings("\u00e9", "\U0001F600"): true,
+			},
+		},
+		{
+			name:       "string greater than or equal",
+			comparator: greaterThanOrEqual,
+			comparisons: map[comparison]bool{
+				compareStrings("b", "a"):  true,
+				compareStrings("a", "a"):  true,
+				compareStrings("a", "ab"): false,
+			},
+		},
 		{
 			name:       "float64 equal",
 			comparator: equal,
@@ -177,9 +197,9 @@ func TestComparators(t *testing.T) {
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.2")): false,
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.1")): false,
 				compareNodeValues(typedValueOfFloat("1.2"), typedValueOfFloat("1.1")): true,
-				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   false, // should be excluded by lexer
-				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // should be excluded by lexer
-				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // should be excluded by lexer
+				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   false,
+				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // numbers and strings are not ordered
+				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // numbers and strings are not ordered
 			},
 		},
 		{
@@ -189,9 +209,9 @@ func TestComparators(t *testing.T) {
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.2")): false,
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.1")): true,
 				compareNodeValues(typedValueOfFloat("1.2"), typedValueOfFloat("1.1")): true,
-				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   true,  // should be excluded by lexer
-				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // should be excluded by lexer
-				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // should be excluded by lexer
+				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   true,
+				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // numbers and strings are not ordered
+				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // numbers and strings are not ordered
 			},
 		},
 		{
@@ -201,9 +221,9 @@ func TestComparators(t *testing.T) {
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.2")): true,
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.1")): false,
 				compareNodeValues(typedValueOfFloat("1.2"), typedValueOfFloat("1.1")): false,
-				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   false, // should be excluded by lexer
-				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // should be excluded by lexer
-				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // should be excluded by lexer
+				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   false,
+				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // numbers and strings are not ordered
+				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // numbers and strings are not ordered
 			},
 		},
 		{
@@ -213,9 +233,9 @@ func TestComparators(t *testing.T) {
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.2")): true,
 				compareNodeValues(typedValueOfFloat("1.1"), typedValueOfFloat("1.1")): true,
 				compareNodeValues(typedValueOfFloat("1.2"), typedValueOfFloat("1.1")): false,
-				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   true,  // should be excluded by lexer
-				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // should be excluded by lexer
-				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // should be excluded by lexer
+				compareNodeValues(typedValueOfString("a"), typedValueOfString("a")):   true,
+				compareNodeValues(typedValueOfFloat("1.0"), typedValueOfString("a")):  false, // numbers and strings are not ordered
+				compareNodeValues(typedValueOfString("a"), typedValueOfFloat("1.0")):  false, // numbers and strings are not ordered
 			},
 		},
 	}
@@ -243,3 +263,105 @@ func TestComparators(t *testing.T) {
 		t.Fatalf("testcase(s) still focussed")
 	}
 }
+
+func TestCompareTemporalValues(t *testing.T) {
+	timestamp := func(s string) typedValue {
+		return newTypedValue(timestampValueType, s)
+	}
+
+	cases := []struct {
+		name     string
+		lhs      typedValue
+		rhs      typedValue
+		expected comparison
+		focus    bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:     "timestamps",
+			lhs:      timestamp("2023-12-31T23:59:59Z"),
+			rhs:      timestamp("2024-01-01T00:00:00Z"),
+			expected: compareLessThan,
+		},
+		{
+			name:     "timestamps in different time zones",
+			lhs:      timestamp("2024-01-01T01:00:00+01:00"),
+			rhs:      timestamp("2024-01-01 00:00:00"),
+			expected: compareEqual,
+		},
+		{
+			name:     "date and timestamp",
+			lhs:      timestamp("2024-01-02"),
+			rhs:      typedValueOfString("2024-01-01T12:00:00.5Z"),
+			expected: compareGreaterThan,
+		},
+		{
+			name:     "strings which are timestamps",
+			lhs:      typedValueOfString("2024-1-1"),
+			rhs:      typedValueOfString("2024-01-01t00:00:00Z"),
+			expected: compareEqual,
+		},
+		{
+			name:     "timestamp and string which is not a timestamp",
+			lhs:      timestamp("2024-01-01"),
+			rhs:      typedValueOfString("yesterday"),
+			expected: compareIncomparable,
+		},
+		{
+			name:     "timestamp and number",
+			lhs:      timestamp("2024-01-01"),
+			rhs:      typedValueOfInt("2024"),
+			expected: compareIncomparable,
+		},
+		{
+			name:     "durations",
+			lhs:      typedValueOfString("90s"),
+			rhs:      typedValueOfString("1m"),
+			expected: compareGreaterThan,
+		},
+		{
+			name:     "equivalent durations",
+			lhs:      typedValueOfString("1h30m"),
+			rhs:      typedValueOfString("90m"),
+			expected: compareEqual,
+		},
+		{
+			name:     "duration and string which is not a duration",
+			lhs:      typedValueOfString("5m"),
+			rhs:      typedValueOfString("5 minutes"),
+			expected: compareIncomparable,
+		},
+		{
+			name:     "unitless number and duration",
+			lhs:      typedValueOfString("0"),
+			rhs:      typedValueOfString("0s"),
+			expected: compareIncomparable,
+		},
+		{
+			name:     "strings which are neither timestamps nor durations",
+			lhs:      typedValueOfString("a"),
+			rhs:      typedValueOfString("b"),
+			expected: compareIncomparable,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, compareTemporalValues(tc.lhs, tc.rhs))
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
@@ -11,11 +11,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-type filter func(node, root *yaml.Node) bool
+type filter func(loc *location, root *yaml.Node) bool
 
 func newFilter(n *filterNode) filter {
 	if n == nil {
@@ -23,10 +24,11 @@ func newFilter(n *filterNode) filter {
 	}
 
 	switch n.lexeme.typ {
-	case lexemeFilterAt, lexemeRoot:
-		path := pathFilterScanner(n)
-		return func(node, root *yaml.Node) bool {
-			return len(path(node, root)) > 0
+	case lexemeFilterAt, lexemeRoot, lexemeFilterKey:
+		path := pathFilterIterator(n)
+		return func(loc *location, root *yaml.Node) bool {
+			_, ok := path(loc, root)()
+			return ok
 		}
 
 	case lexemeFilterEquality, lexemeFilterInequality,
@@ -37,24 +39,34 @@ func newFilter(n *filterNode) filter {
 	case lexemeFilterMatchesRegularExpression:
 		return matchRegularExpression(n)
 
+	case lexemeFilterFunctionName:
+		call, result := functionCall(n)
+		return func(loc *location, root *yaml.Node) bool {
+			r := call(loc, root)
+			if result == NodesType {
+				return len(r.Nodes) > 0
+			}
+			return r.Logical
+		}
+
 	case lexemeFilterNot:
 		f := newFilter(n.children[0])
-		return func(node, root *yaml.Node) bool {
-			return !f(node, root)
+		return func(loc *location, root *yaml.Node) bool {
+			return !f(loc, root)
 		}
 
 	case lexemeFilterOr:
 		f1 := newFilter(n.children[0])
 		f2 := newFilter(n.children[1])
-		return func(node, root *yaml.Node) bool {
-			return f1(node, root) || f2(node, root)
+		return func(loc *location, root *yaml.Node) bool {
+			return f1(loc, root) || f2(loc, root)
 		}
 
 	case lexemeFilterAnd:
 		f1 := newFilter(n.children[0])
 		f2 := newFilter(n.children[1])
-		return func(node, root *yaml.Node) bool {
-			return f1(node, root) && f2(node, root)
+		return func(loc *location, root *yaml.Node) bool {
+			return f1(loc, root) && f2(loc, root)
 		}
 
 	case lexemeFilterBooleanLiteral:
@@ -62,7 +74,7 @@ func newFilter(n *filterNode) filter {
 		if err != nil {
 			panic(err) // should not happen
 		}
-		return func(node, root *yaml.Node) bool {
+		return func(loc *location, root *yaml.Node) bool {
 			return b
 		}
 
@@ -71,7 +83,7 @@ func newFilter(n *filterNode) filter {
 	}
 }
 
-func never(node, root *yaml.Node) bool {
+func never(loc *location, root *yaml.Node) bool {
 	return false
 }
 
@@ -85,6 +97,10 @@ func comparisonFilter(n *filterNode) filter {
 		}
 		return n.lexeme.comparator()(c)
 	}
+	// Timestamps and durations are ordered in time only if one side is a timestamp node or literal, so that equality
+	// and the ordering of other strings are unaffected.
+	ordering := n.lexeme.typ.isOrdering()
+	temporalLiteral := ordering && (n.children[0].isTemporalLiteral() || n.children[1].isTemporalLiteral())
 	return nodeToFilter(n, func(l, r typedValue) bool {
 		if !l.typ.compatibleWith(r.typ) {
 			return compare(false)
@@ -97,6 +113,9 @@ func comparisonFilter(n *filterNode) filter {
 			return compare(equalNulls(l.val, r.val))
 
 		default:
+			if ordering && (temporalLiteral || l.typ == timestampValueType || r.typ == timestampValueType) {
+				return n.lexeme.comparator()(compareTemporalValues(l, r))
+			}
 			return n.lexeme.comparator()(compareNodeValues(l, r))
 		}
 	})
@@ -105,11 +124,11 @@ func comparisonFilter(n *filterNode) filter {
 func nodeToFilter(n *filterNode, accept func(typedValue, typedValue) bool) filter {
 	lhsPath := newFilterScanner(n.children[0])
 	rhsPath := newFilterScanner(n.children[1])
-	return func(node, root *yaml.Node) (result bool) {
+	return func(loc *location, root *yaml.Node) (result bool) {
 		// perform a set-wise comparison of the values in each path
 		match := false
-		for _, l := range lhsPath(node, root) {
-			for _, r := range rhsPath(node, root) {
+		for _, l := range lhsPath(loc, root) {
+			for _, r := range rhsPath(loc, root) {
 				if !accept(l, r) {
 					return false
 				}
@@ -132,9 +151,9 @@ func equalNulls(l, r string) bool {
 
 // filterScanner is a function that returns a slice of typed values from either a filter literal or a path expression
 // which refers to either the current node or the root node. It is used in filter comparisons.
-type filterScanner func(node, root *yaml.Node) []typedValue
+type filterScanner func(loc *location, root *yaml.Node) []typedValue
 
-func emptyScanner(*yaml.Node, *yaml.Node) []typedValue {
+func emptyScanner(*location, *yaml.Node) []typedValue {
 	return []typedValue{}
 }
 
@@ -149,34 +168,65 @@ func newFilterScanner(n *filterNode) filterScanner {
 	case n.isLiteral():
 		return literalFilterScanner(n)
 
+	case n.isFunction():
+		return functionFilterScanner(n)
+
+	case n.isArithmetic():
+		return arithmeticFilterScanner(n)
+
 	default:
 		return emptyScanner
 	}
 }
 
 func pathFilterScanner(n *filterNode) filterScanner {
+	nodes := pathFilterNodes(n)
+	return func(loc *location, root *yaml.Node) []typedValue {
+		return values(nodes(loc, root), nil)
+	}
+}
+
+// pathFilterNodes returns a function which applies the subpath of a root or lexemeFilterAt node.
+func pathFilterNodes(n *filterNode) func(loc *location, root *yaml.Node) []*yaml.Node {
+	path := pathFilterIterator(n)
+	return func(loc *location, root *yaml.Node) []*yaml.Node {
+		return path(loc, root).nodes()
+	}
+}
+
+// pathFilterIterator returns a function which applies the subpath of a root or lexemeFilterAt node lazily. For a
+// lexemeFilterKey node, the function produces the key of the node being filtered, if it is a mapping value.
+func pathFilterIterator(n *filterNode) func(loc *location, root *yaml.Node) locationIterator {
 	var at bool
 	switch n.lexeme.typ {
 	case lexemeFilterAt:
 		at = true
 	case lexemeRoot:
 		at = false
+	case lexemeFilterKey:
+		return func(loc *location, root *yaml.Node) locationIterator {
+			if k := loc.keyLocation(); k != nil {
+				return fromLocations(k)
+			}
+			return fromLocations()
+		}
 	default:
 		panic("false precondition")
 	}
-	subpath := ""
-	for _, lexeme := range n.subpath {
-		subpath += lexeme.val
-	}
-	path, err := NewPath(subpath)
-	if err != nil {
-		return emptyScanner
+	path := n.path
+	if path == nil { // parse tree has not been checked
+		var err error
+		if path, err = n.compileSubpath(&options{}); err != nil {
+			return func(loc *location, root *yaml.Node) locationIterator {
+				return fromLocations()
+			}
+		}
 	}
-	return func(node, root *yaml.Node) []typedValue {
+	return func(loc *location, root *yaml.Node) locationIterator {
 		if at {
-			return values(path.Find(node))
+			return path.f(loc, loc.node)
 		}
-		return values(path.Find(root))
+		return path.f(loc.restart(root), root)
 	}
 }
 
@@ -190,6 +240,7 @@ const (
 	booleanValueType
 	nullValueType
 	regularExpressionValueType
+	timestampValueType
 )
 
 func (vt valueType) isNumeric() bool {
@@ -197,7 +248,14 @@ func (vt valueType) isNumeric() bool {
 }
 
 func (vt valueType) compatibleWith(vt2 valueType) bool {
-	return vt.isNumeric() && vt2.isNumeric() || vt == vt2 || vt == stringValueType && vt2 == regularExpressionValueType
+	return vt.isNumeric() && vt2.isNumeric() || vt == vt2 || vt == stringValueType && vt2 == regularExpressionValueType ||
+		vt.isTemporal() && vt2.isTemporal()
+}
+
+// isTemporal returns true if and only if values of the type may be timestamps. Whether a string is a timestamp is
+// determined when it is compared.
+func (vt valueType) isTemporal() bool {
+	return vt == timestampValueType || vt == stringValueType
 }
 
 type typedValue struct {
@@ -205,12 +263,29 @@ type typedValue struct {
 	val string
 }
 
+// timestamp returns the point in time represented by a timestamp or by a string which is a timestamp.
+func (v typedValue) timestamp() (time.Time, bool) {
+	if !v.typ.isTemporal() {
+		return time.Time{}, false
+	}
+	return parseTimestamp(v.val)
+}
+
+// duration returns the duration represented by a string which is a duration, such as 1h30m.
+func (v typedValue) duration() (time.Duration, bool) {
+	if v.typ != stringValueType {
+		return 0, false
+	}
+	return parseDuration(v.val)
+}
+
 const (
-	nullTag  = "!!null"
-	boolTag  = "!!bool"
-	strTag   = "!!str"
-	intTag   = "!!int"
-	floatTag = "!!float"
+	nullTag      = "!!null"
+	boolTag      = "!!bool"
+	strTag       = "!!str"
+	intTag       = "!!int"
+	floatTag     = "!!float"
+	timestampTag = "!!timestamp"
 )
 
 func typedValueOfNode(node *yaml.Node) typedValue {
@@ -231,6 +306,9 @@ func typedValueOfNode(node *yaml.Node) typedValue {
 
 		case floatTag:
 			t = floatValueType
+
+		case timestampTag:
+			t = timestampValueType
 		}
 	}
 
@@ -272,13 +350,111 @@ func values(nodes []*yaml.Node, err error) []typedValue {
 
 func literalFilterScanner(n *filterNode) filterScanner {
 	v := n.lexeme.literalValue()
-	return func(node, root *yaml.Node) []typedValue {
+	return func(loc *location, root *yaml.Node) []typedValue {
 		return []typedValue{v}
 	}
 }
 
+func functionFilterScanner(n *filterNode) filterScanner {
+	call, _ := functionCall(n)
+	return func(loc *location, root *yaml.Node) []typedValue {
+		if v := call(loc, root).Value; v != nil {
+			return []typedValue{typedValueOfNode(v)}
+		}
+		return []typedValue{}
+	}
+}
+
+// functionCall returns a function which evaluates the arguments of a function extension and calls the function,
+// together with the function's result type. The parse tree must already have been checked.
+func functionCall(n *filterNode) (functionCallFunc, FunctionType) {
+	f := n.function
+	args := []functionCallFunc{}
+	literals := []*yaml.Node{}
+	for i, c := range n.children {
+		args = append(args, functionArgument(c, f.params[i]))
+		var literal *yaml.Node
+		if c.isLiteral() && f.params[i] == ValueType {
+			literal = literalNode(c.lexeme.literalValue())
+		}
+		literals = append(literals, literal)
+	}
+	call := f.implementation(literals)
+	return func(loc *location, root *yaml.Node) FunctionValue {
+		vals := []FunctionValue{}
+		for _, a := range args {
+			vals = append(vals, a(loc, root))
+		}
+		return call(loc.eval, vals)
+	}, f.result
+}
+
+// functionArgument returns a function which evaluates an argument of a function extension with the given parameter
+// type.
+func functionArgument(n *filterNode, t FunctionType) functionCallFunc {
+	if t == LogicalType {
+		f := newFilter(n)
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			return FunctionValue{Logical: f(loc, root)}
+		}
+	}
+
+	if n.isFunction() {
+		call, _ := functionCall(n)
+		return call
+	}
+
+	if n.isLiteral() {
+		v := literalNode(n.lexeme.literalValue())
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			return FunctionValue{Value: v}
+		}
+	}
+
+	if n.isArithmetic() {
+		scan := arithmeticFilterScanner(n)
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			if v := scan(loc, root); len(v) > 0 {
+				return FunctionValue{Value: literalNode(v[0])}
+			}
+			return FunctionValue{}
+		}
+	}
+
+	if t == NodesType {
+		nodes := pathFilterNodes(n)
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			return FunctionValue{Nodes: nodes(loc, root)}
+		}
+	}
+	path := pathFilterIterator(n)
+	return func(loc *location, root *yaml.Node) FunctionValue {
+		if l, ok := path(loc, root)(); ok {
+			return l.valueArgument()
+		}
+		return FunctionValue{}
+	}
+}
+
+// literalNode converts a literal value to a YAML node.
+func literalNode(v typedValue) *yaml.Node {
+	tags := map[valueType]string{
+		stringValueType:  strTag,
+		intValueType:     intTag,
+		floatValueType:   floatTag,
+		booleanValueType: boolTag,
+		nullValueType:    nullTag,
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tags[v.typ], Value: v.val}
+}
+
 func matchRegularExpression(parseTree *filterNode) filter {
-	return nodeToFilter(parseTree, stringMatchesRegularExpression)
+	match := nodeToFilter(parseTree, stringMatchesRegularExpression)
+	size := regexpProgramSize(parseTree.children[1].lexeme.literalValue().val)
+	return func(loc *location, root *yaml.Node) bool {
+		loc.eval.checkRegexpSize(size)
+		return match(loc, root)
+	}
 }
 
 func stringMatchesRegularExpression(s, expr typedValue) bool {
@@ -6,10 +6,17 @@
 
 package yamlpath
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 /*
    filterNode represents a node of a filter expression parse tree. Each node is labelled with a lexeme.
 
-   Terminal nodes have one of the following lexemes: root, lexemeFilterAt, lexemeFilterIntegerLiteral,
+   Terminal nodes have one of the following lexemes: root, lexemeFilterAt, lexemeFilterKey, lexemeFilterIntegerLiteral,
    lexemeFilterFloatLiteral, lexemeFilterStringLiteral, lexemeFilterBooleanLiteral.
    root and lexemeFilterAt nodes also have a slice of lexemes representing the subpath of `$`` or `@``,
    respectively.
@@ -62,19 +69,85 @@ package yamlpath
        @.child   5  @.child   10
 
    Note that brackets do not appear in the parse tree.
+
+   Arithmetic operators bind more tightly than comparisons, and *, /, and % bind more tightly than + and -. For
+   example, the filter expression `@.a + @.b * 2 > 10` is represented as the parse tree:
+
+       lexemeFilterGreaterThan<lexemeFilterAdd<lexemeFilterAt,lexemeFilterMultiply<lexemeFilterAt,
+                                                                                   lexemeFilterIntegerLiteral>>,
+                               lexemeFilterIntegerLiteral>
+
+   or, graphically:
+
+                      >
+                    /   \
+                  +      10
+                /   \
+             @.a     *
+                    / \
+                 @.b   2
+
+   Unary minus is represented as a node labelled with a lexemeFilterUnaryMinus lexeme with a single child.
+
+   A function extension is represented as a node labelled with a lexemeFilterFunctionName lexeme whose children are
+   the function's arguments. For example, the filter expression `length(@.child) > 3` is represented as the parse
+   tree:
+
+       lexemeFilterGreaterThan<lexemeFilterFunctionName<lexemeFilterAt>,lexemeFilterIntegerLiteral>
+
+   or, graphically:
+
+                   >
+                 /   \
+           length     3
+             |
+          @.child
 */
 type filterNode struct {
 	lexeme   lexeme
 	subpath  []lexeme // empty unless lexeme is root or lexemeFilterAt
 	children []*filterNode
+
+	// the following are set when the parse tree is checked
+	function *function // the function named by a lexemeFilterFunctionName node
+	path     *Path     // the compiled subpath of a root or lexemeFilterAt node
 }
 
 func newFilterNode(lexemes []lexeme) *filterNode {
 	return newParser(lexemes).parse()
 }
 
+// parseFilter parses the lexemes of a filter and checks the resultant parse tree using the given options.
+func parseFilter(lexemes []lexeme, o *options) (*filterNode, error) {
+	p := newParser(lexemes)
+	tree := p.parse()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := tree.checkLogical(o); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
 func (n *filterNode) isItemFilter() bool {
-	return n.lexeme.typ == lexemeFilterAt || n.lexeme.typ == lexemeRoot
+	return n.lexeme.typ == lexemeFilterAt || n.lexeme.typ == lexemeRoot || n.lexeme.typ == lexemeFilterKey
+}
+
+// refersToKey returns true if and only if the parse tree refers to the key of the node being filtered using `@~`.
+func (n *filterNode) refersToKey() bool {
+	if n == nil {
+		return false
+	}
+	if n.lexeme.typ == lexemeFilterKey {
+		return true
+	}
+	for _, c := range n.children {
+		if c.refersToKey() {
+			return true
+		}
+	}
+	return false
 }
 
 func (n *filterNode) isLiteral() bool {
@@ -85,6 +158,17 @@ func (n *filterNode) isStringLiteral() bool {
 	return n.lexeme.typ == lexemeFilterStringLiteral
 }
 
+// isTemporalLiteral returns true if and only if the node is a string literal which is a timestamp or a duration.
+func (n *filterNode) isTemporalLiteral() bool {
+	if !n.isStringLiteral() {
+		return false
+	}
+	v := n.lexeme.literalValue()
+	_, isTimestamp := v.timestamp()
+	_, isDuration := v.duration()
+	return isTimestamp || isDuration
+}
+
 func (n *filterNode) isBooleanLiteral() bool {
 	return n.lexeme.typ == lexemeFilterBooleanLiteral
 }
@@ -101,12 +185,215 @@ func (n *filterNode) isRegularExpressionLiteral() bool {
 	return n.lexeme.typ == lexemeFilterRegularExpressionLiteral
 }
 
+func (n *filterNode) isFunction() bool {
+	return n.lexeme.typ == lexemeFilterFunctionName
+}
+
+func (n *filterNode) isArithmetic() bool {
+	return n.lexeme.typ.isArithmetic()
+}
+
+// isLogical returns true if and only if the node is a logical operator or a comparison.
+func (n *filterNode) isLogical() bool {
+	switch n.lexeme.typ {
+	case lexemeFilterNot, lexemeFilterAnd, lexemeFilterOr:
+		return true
+	}
+	return n.lexeme.typ.isComparisonOrMatch()
+}
+
+// compileSubpath compiles the subpath of a root or lexemeFilterAt node.
+func (n *filterNode) compileSubpath(o *options) (*Path, error) {
+	subpath := ""
+	for _, lexeme := range n.subpath {
+		subpath += lexeme.val
+	}
+	return compile(subpath, o)
+}
+
+// checkSubpath compiles the subpath of a root or lexemeFilterAt node and records the result in the node.
+func (n *filterNode) checkSubpath(o *options) error {
+	path, err := n.compileSubpath(o)
+	if err != nil {
+		return err
+	}
+	n.path = path
+	return nil
+}
+
+// isSingular returns true if and only if the subpath of a root or lexemeFilterAt node can match at most one node.
+func (n *filterNode) isSingular() bool {
+	for _, l := range n.subpath {
+		switch l.typ {
+		case lexemeIdentity, lexemeParent:
+
+		case lexemeDotChild, lexemePropertyName:
+			if strings.TrimSuffix(l.val, propertyName) == ".*" {
+				return false
+			}
+
+		case lexemeBracketChild, lexemeBracketPropertyName:
+			childNames := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(strings.TrimSpace(l.val), propertyName), "["), "]")
+			if len(bracketChildNames(childNames)) != 1 {
+				return false
+			}
+
+		case lexemeArraySubscript:
+			subscript := strings.TrimSuffix(strings.TrimPrefix(l.val, "["), "]")
+			if _, err := strconv.Atoi(strings.TrimSpace(subscript)); err != nil {
+				return false
+			}
+
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkLogical checks a parse tree which is used as a filter, or as an argument of type LogicalType, according to the
+// type system of RFC 9535.
+func (n *filterNode) checkLogical(o *options) error {
+	if n == nil {
+		return nil
+	}
+	switch n.lexeme.typ {
+	case lexemeFilterNot, lexemeFilterAnd, lexemeFilterOr:
+		for _, c := range n.children {
+			if err := c.checkLogical(o); err != nil {
+				return err
+			}
+		}
+
+	case lexemeFilterEquality, lexemeFilterInequality,
+		lexemeFilterGreaterThan, lexemeFilterGreaterThanOrEqual,
+		lexemeFilterLessThan, lexemeFilterLessThanOrEqual,
+		lexemeFilterMatchesRegularExpression:
+		for _, c := range n.children {
+			if err := c.checkComparable(o); err != nil {
+				return err
+			}
+		}
+
+	case lexemeFilterFunctionName:
+		f, err := n.checkFunction(o)
+		if err != nil {
+			return err
+		}
+		if f.result == ValueType {
+			return fmt.Errorf("result of function %s() must be compared", n.lexeme.val)
+		}
+
+	case lexemeFilterAt, lexemeRoot, lexemeFilterKey:
+		return n.checkSubpath(o)
+
+	default:
+		if n.isArithmetic() {
+			return errors.New("result of arithmetic expression must be compared")
+		}
+	}
+	return nil
+}
+
+// checkComparable checks a parse tree which is an operand of a comparison.
+func (n *filterNode) checkComparable(o *options) error {
+	switch {
+	case n == nil:
+		return nil
+
+	case n.isFunction():
+		f, err := n.checkFunction(o)
+		if err != nil {
+			return err
+		}
+		if f.result != ValueType {
+			return fmt.Errorf("result of function %s() is of type %s and cannot be compared", n.lexeme.val, f.result)
+		}
+
+	case n.isItemFilter():
+		return n.checkSubpath(o)
+
+	case n.isArithmetic():
+		for _, c := range n.children {
+			if err := c.checkComparable(o); err != nil {
+				return err
+			}
+		}
+
+	case n.isLogical():
+		return errors.New("result of logical expression cannot be compared")
+	}
+	return nil
+}
+
+// checkFunction checks a function extension and its arguments and returns the function.
+func (n *filterNode) checkFunction(o *options) (*function, error) {
+	name := n.lexeme.val
+	f, ok := o.function(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", name) // should not happen, the lexer checks function names
+	}
+	if len(n.children) != len(f.params) {
+		return nil, fmt.Errorf("function %s() takes %d argument(s)", name, len(f.params))
+	}
+	for i, c := range n.children {
+		if err := c.checkArgument(f.params[i], o); err != nil {
+			return nil, fmt.Errorf("argument %d of function %s(): %s", i+1, name, err)
+		}
+	}
+	n.function = f
+	return f, nil
+}
+
+// checkArgument checks a parse tree which is an argument of a function extension with the given parameter type.
+func (n *filterNode) checkArgument(t FunctionType, o *options) error {
+	if n.isFunction() {
+		f, err := n.checkFunction(o)
+		if err != nil {
+			return err
+		}
+		if f.result != t && !(t == LogicalType && f.result == NodesType) {
+			return fmt.Errorf("result of function %s() is of type %s but %s is required", n.lexeme.val, f.result, t)
+		}
+		return nil
+	}
+
+	switch t {
+	case ValueType:
+		switch {
+		case n.isItemFilter():
+			if !n.isSingular() {
+				return errors.New("query must be singular")
+			}
+			return n.checkSubpath(o)
+
+		case n.isLiteral() && !n.isRegularExpressionLiteral():
+			return nil
+
+		case n.isArithmetic():
+			return n.checkComparable(o)
+		}
+
+	case LogicalType:
+		if !n.isLiteral() {
+			return n.checkLogical(o)
+		}
+
+	case NodesType:
+		if n.isItemFilter() {
+			return n.checkSubpath(o)
+		}
+	}
+	return fmt.Errorf("%s is required", t)
+}
+
 // parser holds the state of the filter expression parser.
 type parser struct {
 	input []lexeme      // the lexemes being scanned
 	pos   int           // current position in the input
 	stack []*filterNode // parser stack
 	tree  *filterNode   // parse tree
+	err   error         // the first error detected, if any
 }
 
 // newParser creates a new parser for the input slice of lexemes.
@@ -147,11 +434,21 @@ func (p *parser) peek() lexeme {
 	return p.input[p.pos]
 }
 
+// fail records an error unless one has already been recorded.
+func (p *parser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
 func (p *parser) parse() *filterNode {
 	if p.peek().typ == lexemeEOF {
 		return nil
 	}
 	p.expression()
+	if p.peek().typ == lexemeFilterArgumentSeparator {
+		p.fail("unexpected %q outside function arguments", filterArgumentSeparator)
+	}
 	return p.tree
 }
 
@@ -200,8 +497,7 @@ func (p *parser) and() {
 // basicFilter consumes then next basic filter and sets it as the parser's tree. If a basic filter it not next, nil is set.
 func (p *parser) basicFilter() {
 	n := p.peek()
-	switch n.typ {
-	case lexemeFilterNot:
+	if n.typ == lexemeFilterNot {
 		p.nextLexeme()
 		p.basicFilter()
 		p.tree = &filterNode{
@@ -212,22 +508,14 @@ func (p *parser) basicFilter() {
 			},
 		}
 		return
-
-	case lexemeFilterOpenBracket:
-		p.nextLexeme()
-		p.expression()
-		if p.peek().typ == lexemeFilterCloseBracket {
-			p.nextLexeme()
-		}
-		return
 	}
 
-	p.filterTerm()
+	p.sum()
 	n = p.peek()
 	if n.typ.isComparisonOrMatch() {
 		p.nextLexeme()
 		filterTerm := p.tree
-		p.filterTerm()
+		p.sum()
 		p.tree = &filterNode{
 			lexeme:  n,
 			subpath: []lexeme{},
@@ -239,6 +527,71 @@ func (p *parser) basicFilter() {
 	}
 }
 
+// sum consumes a sequence of products separated by + or - and sets the parser's tree to the resultant arithmetic
+// expression, which associates to the left.
+func (p *parser) sum() {
+	p.product()
+	for p.peek().typ == lexemeFilterAdd || p.peek().typ == lexemeFilterSubtract {
+		p.push(p.tree)
+		p.arithmetic(p.product)
+	}
+}
+
+// product consumes a sequence of unary expressions separated by *, /, or % and sets the parser's tree to the
+// resultant arithmetic expression, which associates to the left.
+func (p *parser) product() {
+	p.unary()
+	for p.peek().typ == lexemeFilterMultiply || p.peek().typ == lexemeFilterDivide || p.peek().typ == lexemeFilterModulo {
+		p.push(p.tree)
+		p.arithmetic(p.unary)
+	}
+}
+
+// arithmetic consumes a binary arithmetic operator followed by an operand, parsed by the given function, and
+// combines the operand with the left operand on the parser stack.
+func (p *parser) arithmetic(operand func()) {
+	n := p.nextLexeme()
+	operand()
+	p.tree = &filterNode{
+		lexeme:  n,
+		subpath: []lexeme{},
+		children: []*filterNode{
+			p.pop(),
+			p.tree,
+		},
+	}
+}
+
+// unary consumes a filter term, or a bracketed filter expression, preceded by any number of unary minus operators.
+func (p *parser) unary() {
+	n := p.peek()
+	switch n.typ {
+	case lexemeFilterUnaryMinus:
+		p.nextLexeme()
+		p.unary()
+		p.tree = &filterNode{
+			lexeme:  n,
+			subpath: []lexeme{},
+			children: []*filterNode{
+				p.tree,
+			},
+		}
+
+	case lexemeFilterOpenBracket:
+		p.nextLexeme()
+		p.expression()
+		switch p.peek().typ {
+		case lexemeFilterCloseBracket:
+			p.nextLexeme()
+		case lexemeFilterArgumentSeparator:
+			p.fail("unexpected %q outside function arguments", filterArgumentSeparator)
+		}
+
+	default:
+		p.filterTerm()
+	}
+}
+
 // filterTerm consumes the next filter term and sets it as the parser's tree. If a filter term is not next, nil is set.
 func (p *parser) filterTerm() {
 	n := p.peek()
@@ -254,7 +607,10 @@ func (p *parser) filterTerm() {
 		for {
 			s := p.peek()
 			switch s.typ {
-			case lexemeIdentity, lexemeDotChild, lexemeBracketChild, lexemeRecursiveDescent, lexemeArraySubscript:
+			case lexemeIdentity, lexemeDotChild, lexemeBracketChild, lexemeRecursiveDescent, lexemeArraySubscript,
+				lexemeTagSelector, lexemeParent, lexemeAncestors, lexemePropertyName, lexemeBracketPropertyName,
+				lexemeArraySubscriptPropertyName, lexemeFilterPropertyName, lexemeUnionBegin, lexemeUnionName,
+				lexemeUnionSubscript, lexemeUnionSeparator, lexemeUnionEnd:
 
 			case lexemeFilterBegin:
 				filterNestingLevel++
@@ -284,12 +640,44 @@ func (p *parser) filterTerm() {
 		}
 
 	case lexemeFilterIntegerLiteral, lexemeFilterFloatLiteral, lexemeFilterStringLiteral, lexemeFilterBooleanLiteral,
-		lexemeFilterNullLiteral, lexemeFilterRegularExpressionLiteral:
+		lexemeFilterNullLiteral, lexemeFilterRegularExpressionLiteral, lexemeFilterKey:
 		p.nextLexeme()
 		p.tree = &filterNode{
 			lexeme:   n,
 			subpath:  []lexeme{},
 			children: []*filterNode{},
 		}
+
+	case lexemeFilterFunctionName:
+		p.nextLexeme()
+		p.nextLexeme() // the lexer emits an open bracket after each function name
+		args := []*filterNode{}
+		if p.peek().typ == lexemeFilterCloseBracket {
+			p.nextLexeme()
+		} else {
+			for {
+				p.tree = nil
+				p.expression()
+				if p.tree == nil {
+					p.fail("missing argument of function %s()", n.val)
+				}
+				args = append(args, p.tree)
+				if p.peek().typ == lexemeFilterArgumentSeparator {
+					p.nextLexeme()
+					continue
+				}
+				if p.peek().typ != lexemeFilterCloseBracket {
+					p.fail("missing %q after arguments of function %s()", filterCloseBracket, n.val)
+					break
+				}
+				p.nextLexeme()
+				break
+			}
+		}
+		p.tree = &filterNode{
+			lexeme:   n,
+			subpath:  []lexeme{},
+			children: args,
+		}
 	}
 }
@@ -705,6 +705,84 @@ func TestNewFilterNode(t *testing.T) {
 				children: []*filterNode{},
 			},
 		},
+		{
+			name: "function with arguments in comparison",
+			lexemes: []lexeme{
+				{typ: lexemeFilterFunctionName, val: "match"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeFilterArgumentSeparator, val: ","},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterOr, val: "||"},
+				{typ: lexemeFilterFunctionName, val: "length"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterGreaterThan, val: ">"},
+				{typ: lexemeFilterIntegerLiteral, val: "1"},
+			},
+			expected: &filterNode{
+				lexeme:  lexeme{typ: lexemeFilterOr, val: "||"},
+				subpath: []lexeme{},
+				children: []*filterNode{
+					{
+						lexeme:  lexeme{typ: lexemeFilterFunctionName, val: "match"},
+						subpath: []lexeme{},
+						children: []*filterNode{
+							{
+								lexeme: lexeme{typ: lexemeFilterAt, val: "@"},
+								subpath: []lexeme{
+									{typ: lexemeDotChild, val: ".a"},
+								},
+								children: []*filterNode{},
+							},
+							{
+								lexeme:   lexeme{typ: lexemeFilterStringLiteral, val: "'x'"},
+								subpath:  []lexeme{},
+								children: []*filterNode{},
+							},
+						},
+					},
+					{
+						lexeme:  lexeme{typ: lexemeFilterGreaterThan, val: ">"},
+						subpath: []lexeme{},
+						children: []*filterNode{
+							{
+								lexeme:  lexeme{typ: lexemeFilterFunctionName, val: "length"},
+								subpath: []lexeme{},
+								children: []*filterNode{
+									{
+										lexeme:   lexeme{typ: lexemeFilterAt, val: "@"},
+										subpath:  []lexeme{},
+										children: []*filterNode{},
+									},
+								},
+							},
+							{
+								lexeme:   lexeme{typ: lexemeFilterIntegerLiteral, val: "1"},
+								subpath:  []lexeme{},
+								children: []*filterNode{},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "function without arguments",
+			lexemes: []lexeme{
+				{typ: lexemeFilterFunctionName, val: "f"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+			},
+			expected: &filterNode{
+				lexeme:   lexeme{typ: lexemeFilterFunctionName, val: "f"},
+				subpath:  []lexeme{},
+				children: []*filterNode{},
+			},
+		},
 		{
 			name: "unexpected close bracket (edge case, garbage in garbage out)",
 			lexemes: []lexeme{
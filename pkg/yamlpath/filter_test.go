@@ -421,6 +421,42 @@ x: 'a'
 			yamlDoc: `---
 x: a
 y: b
+`,
+			match: false,
+		},
+		{
+			name:   "string ordering filter, path to literal, match",
+			filter: "@.x<'b' && @.x>='B' && @.x>'a' && @.x<=@.y",
+			yamlDoc: `---
+x: ab
+y: ab
+`,
+			match: true,
+		},
+		{
+			name:   "string ordering filter, path to path, no match",
+			filter: "@.x>@.y",
+			yamlDoc: `---
+x: a
+y: ab
+`,
+			match: false,
+		},
+		{
+			name:   "string ordering filter, unitless number string literals, match",
+			filter: "@.x>'0' && @.y>='0' && @.y<'1' && @.z>'1' && @.z>'1.5'",
+			yamlDoc: `---
+x: b
+y: "0"
+z: "10"
+`,
+			match: true,
+		},
+		{
+			name:   "string ordering filter, string to number, no match",
+			filter: "@.x>'1' || @.x<'1'",
+			yamlDoc: `---
+x: 2
 `,
 			match: false,
 		},
@@ -682,6 +718,28 @@ category: reference
 author: Nigel Rees
 title: Sayings of the Century
 price: 8.95
+`,
+			match: false,
+		},
+		{
+			name:   "regular expression filter ignoring case, match",
+			filter: "@.author=~/^nigel/i",
+			yamlDoc: `---
+category: reference
+author: Nigel Rees
+title: Sayings of the Century
+price: 8.95
+`,
+			match: true,
+		},
+		{
+			name:   "regular expression filter without ignoring case, no match",
+			filter: "@.author=~/^nigel/",
+			yamlDoc: `---
+category: reference
+author: Nigel Rees
+title: Sayings of the Century
+price: 8.95
 `,
 			match: false,
 		},
@@ -704,6 +762,107 @@ category: reference
 author: Nigel Rees
 title: Sayings of the Century
 price: 8.95
+`,
+			match: false,
+		},
+		{
+			name:   "arithmetic multiplication, match",
+			filter: "@.limits.cpu > @.requests.cpu * 2",
+			yamlDoc: `---
+limits:
+  cpu: 5
+requests:
+  cpu: 2
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic addition, no match",
+			filter: "@.replicas + @.surge <= 10",
+			yamlDoc: `---
+replicas: 8
+surge: 3
+`,
+			match: false,
+		},
+		{
+			name:   "arithmetic precedence and brackets",
+			filter: "(@.a + 1) * 2 == 8 && @.a + 1 * 2 == 5 && @.a - 1 - 1 == 1",
+			yamlDoc: `---
+a: 3
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic inexact integer division",
+			filter: "@.a / 2 == 1.5 && @.a / 3 == 1",
+			yamlDoc: `---
+a: 3
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic remainder and unary minus",
+			filter: "-@.a % 2 == -1 && - -@.a == 3 && -(@.a - 5) == 2",
+			yamlDoc: `---
+a: 3
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic with floats",
+			filter: "@.a * 2 == 3 && @.a - 0.5 == 1",
+			yamlDoc: `---
+a: 1.5
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic with hexadecimal integer",
+			filter: "@.a + 1 == 32",
+			yamlDoc: `---
+a: 0x1F
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic overflow produces float",
+			filter: "@.a * 2 > 9223372036854775807",
+			yamlDoc: `---
+a: 9223372036854775807
+`,
+			match: true,
+		},
+		{
+			name:   "string concatenation",
+			filter: "@.first + ' ' + @.last == 'Nigel Rees'",
+			yamlDoc: `---
+first: Nigel
+last: Rees
+`,
+			match: true,
+		},
+		{
+			name:   "arithmetic with non-numeric operand, no match",
+			filter: "@.a + 1 > 0 || @.a + 1 <= 0 || @.a + 1 == 'x1' || -@.a == 'x'",
+			yamlDoc: `---
+a: x
+`,
+			match: false,
+		},
+		{
+			name:   "arithmetic division by zero, no match",
+			filter: "@.a / 0 > 0 || @.a / 0 <= 0 || @.a % 0 == 0",
+			yamlDoc: `---
+a: 1
+`,
+			match: false,
+		},
+		{
+			name:   "arithmetic with missing operand, no match",
+			filter: "@.a + @.b >= 0",
+			yamlDoc: `---
+a: 1
 `,
 			match: false,
 		},
@@ -742,7 +901,7 @@ price: 8.95
 			root := unmarshalDoc(t, tc.rootDoc)
 
 			parseTree := parseFilterString(tc.filter)
-			match := newFilter(parseTree)(n, root)
+			match := newFilter(parseTree)(&location{node: n}, root)
 			require.Equal(t, tc.match, match)
 		})
 	}
@@ -752,6 +911,92 @@ price: 8.95
 	}
 }
 
+func TestIRegexpMatcher(t *testing.T) {
+	cases := []struct {
+		name           string
+		literal        string // the literal regular expression, if any
+		entire         bool
+		patterns       []string // the regular expression argument of each call
+		subject        string
+		expected       []bool // the result of each call
+		expectedCached int    // the number of cached regular expressions
+		focus          bool   // if true, run only tests with focus set to true
+	}{
+		{
+			name:           "literal",
+			literal:        "a.c",
+			entire:         true,
+			patterns:       []string{"a.c", "a.c"},
+			subject:        "abc",
+			expected:       []bool{true, true},
+			expectedCached: 0,
+		},
+		{
+			name:           "invalid literal",
+			literal:        "(",
+			entire:         true,
+			patterns:       []string{"("},
+			subject:        "(",
+			expected:       []bool{false},
+			expectedCached: 0,
+		},
+		{
+			name:           "non-literals",
+			patterns:       []string{"b", "x", "b"},
+			subject:        "abc",
+			expected:       []bool{true, false, true},
+			expectedCached: 2,
+		},
+		{
+			name:           "invalid non-literal",
+			patterns:       []string{"(", "("},
+			subject:        "(",
+			expected:       []bool{false, false},
+			expectedCached: 1,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var literal *yaml.Node
+			if tc.literal != "" {
+				literal = strNode(tc.literal)
+			}
+			m := newIRegexpMatcher(literal, tc.entire)
+			actual := []bool{}
+			for _, pattern := range tc.patterns {
+				actual = append(actual, m.match(nil, []FunctionValue{{Value: strNode(tc.subject)}, {Value: strNode(pattern)}}).Logical)
+			}
+			require.Equal(t, tc.expected, actual)
+			require.Len(t, m.cache, tc.expectedCached)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestIRegexpMatcherCacheIsBounded(t *testing.T) {
+	m := newIRegexpMatcher(nil, false)
+	for i := 0; i < 2*maxCachedIRegexps; i++ {
+		pattern := strNode(fmt.Sprintf("x%d", i))
+		require.False(t, m.match(nil, []FunctionValue{{Value: strNode("y")}, {Value: pattern}}).Logical)
+		require.LessOrEqual(t, len(m.cache), maxCachedIRegexps)
+	}
+}
+
 func unmarshalDoc(t *testing.T, doc string) *yaml.Node {
 	var n yaml.Node
 	err := yaml.Unmarshal([]byte(doc), &n)
@@ -0,0 +1,507 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FunctionType is the declared type of a parameter or result of a filter function, as defined by RFC 9535.
+type FunctionType int
+
+const (
+	// ValueType is the type of a single value, represented by a node, or Nothing, represented by nil.
+	ValueType FunctionType = iota
+
+	// LogicalType is the type of true and false.
+	LogicalType
+
+	// NodesType is the type of a list of nodes, such as the nodes matched by a path in a filter.
+	NodesType
+)
+
+func (t FunctionType) String() string {
+	switch t {
+	case ValueType:
+		return "ValueType"
+	case LogicalType:
+		return "LogicalType"
+	case NodesType:
+		return "NodesType"
+	default:
+		return fmt.Sprintf("FunctionType(%d)", int(t))
+	}
+}
+
+func (t FunctionType) valid() bool {
+	return t >= ValueType && t <= NodesType
+}
+
+// FunctionSignature declares the types of the parameters and the result of a filter function.
+type FunctionSignature struct {
+	Params []FunctionType
+	Result FunctionType
+}
+
+// FunctionValue is an argument or result of a filter function. Only the field corresponding to the declared type
+// of the parameter or result is used.
+type FunctionValue struct {
+	Value   *yaml.Node   // ValueType: the value, or nil if the value is Nothing
+	Logical bool         // LogicalType
+	Nodes   []*yaml.Node // NodesType
+
+	key *yaml.Node // the key of Value if Value was produced by a query and is a mapping value, otherwise nil
+}
+
+// Scalar decodes a Value which is a scalar node into a string, int, float64, bool, or nil, according to the node's
+// tag. It returns false if the Value is Nothing or is not a scalar node.
+func (v FunctionValue) Scalar() (interface{}, bool) {
+	if v.Value == nil {
+		return nil, false
+	}
+	n := dealias(v.Value)
+	if n.Kind != yaml.ScalarNode {
+		return nil, false
+	}
+	var s interface{}
+	if err := n.Decode(&s); err != nil {
+		return n.Value, true // e.g. a timestamp which cannot be decoded into an interface{}
+	}
+	return s, true
+}
+
+// ScalarValue returns a FunctionValue whose Value is a scalar node representing the given string, integer, float,
+// bool, or nil.
+func ScalarValue(s interface{}) FunctionValue {
+	var n yaml.Node
+	if err := n.Encode(s); err != nil || n.Kind != yaml.ScalarNode {
+		panic(fmt.Sprintf("ScalarValue called with non-scalar %#v", s))
+	}
+	return FunctionValue{Value: &n}
+}
+
+// Function is the implementation of a filter function. It is passed an argument of the declared type for each
+// parameter and must return a result of the declared result type.
+type Function func(args []FunctionValue) FunctionValue
+
+// functionCallFunc evaluates an argument of a filter function or calls the function.
+type functionCallFunc func(loc *location, root *yaml.Node) FunctionValue
+
+// function is a filter function which may be called in a filter expression.
+type function struct {
+	params []FunctionType
+	result FunctionType
+	call   functionImpl
+
+	// bind, if not nil, is used instead of call to obtain the implementation of each call of the function in a
+	// filter expression. It is passed the arguments of the call which are literals, and nil for the other arguments,
+	// so that, for example, a literal regular expression can be compiled once rather than each time it is used.
+	bind func(literals []*yaml.Node) functionImpl
+}
+
+// implementation returns the implementation of a call of the function with the given literal arguments.
+func (f *function) implementation(literals []*yaml.Node) functionImpl {
+	if f.bind != nil {
+		return f.bind(literals)
+	}
+	return f.call
+}
+
+// functionImpl is the internal form of the implementation of a filter function. It is passed the evaluation, if any,
+// in which the function is called, so that the function can respect the evaluation's limits.
+type functionImpl func(e *evaluation, args []FunctionValue) FunctionValue
+
+// ignoringEvaluation converts a Function into a functionImpl which does not depend on the evaluation.
+func ignoringEvaluation(f Function) functionImpl {
+	return func(_ *evaluation, args []FunctionValue) FunctionValue {
+		return f(args)
+	}
+}
+
+// standardFunctions are the function extensions defined by RFC 9535.
+var standardFunctions = map[string]*function{
+	"length": {
+		params: []FunctionType{ValueType},
+		result: ValueType,
+		call:   ignoringEvaluation(lengthFunction),
+	},
+	"count": {
+		params: []FunctionType{NodesType},
+		result: ValueType,
+		call:   ignoringEvaluation(countFunction),
+	},
+	"match": {
+		params: []FunctionType{ValueType, ValueType},
+		result: LogicalType,
+		bind: func(literals []*yaml.Node) functionImpl {
+			return newIRegexpMatcher(literals[1], true).match
+		},
+	},
+	"search": {
+		params: []FunctionType{ValueType, ValueType},
+		result: LogicalType,
+		bind: func(literals []*yaml.Node) functionImpl {
+			return newIRegexpMatcher(literals[1], false).match
+		},
+	},
+	"value": {
+		params: []FunctionType{NodesType},
+		result: ValueType,
+		call:   ignoringEvaluation(valueFunction),
+	},
+}
+
+// yamlFunctions are function extensions, in addition to those defined by RFC 9535, for YAML features which have
+// no counterpart in JSON.
+var yamlFunctions = map[string]*function{
+	"anchor": {
+		params: []FunctionType{ValueType},
+		result: ValueType,
+		call:   ignoringEvaluation(anchorFunction),
+	},
+	"alias": {
+		params: []FunctionType{ValueType},
+		result: ValueType,
+		call:   ignoringEvaluation(aliasFunction),
+	},
+	"tag": {
+		params: []FunctionType{ValueType},
+		result: ValueType,
+		call:   ignoringEvaluation(tagFunction),
+	},
+	"kind": {
+		params: []FunctionType{ValueType},
+		result: ValueType,
+		call:   ignoringEvaluation(kindFunction),
+	},
+	"comment": {
+		params: []FunctionType{ValueType},
+		result: ValueType,
+		call:   ignoringEvaluation(commentFunction),
+	},
+}
+
+var functionNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// WithFunction is an Option which defines a filter function, in addition to the function extensions defined by
+// RFC 9535, with the given name, signature, and implementation. The name must consist of lowercase ASCII letters,
+// digits, and underscores, starting with a letter, and must not be the name of a function extension defined by
+// RFC 9535 or of one of the functions anchor(), alias(), tag(), kind(), and comment().
+//
+// Calls of the function are type checked against the signature, in the same way as calls of the function
+// extensions defined by RFC 9535, when the path is constructed.
+func WithFunction(name string, signature FunctionSignature, impl Function) Option {
+	return func(o *options) {
+		if o.err != nil {
+			return
+		}
+		if !functionNameRegexp.MatchString(name) {
+			o.err = fmt.Errorf("invalid function name %q", name)
+			return
+		}
+		if _, ok := predefinedFunction(name); ok {
+			o.err = fmt.Errorf("function %s() cannot be redefined", name)
+			return
+		}
+		if impl == nil {
+			o.err = fmt.Errorf("function %s() has no implementation", name)
+			return
+		}
+		for _, t := range append(signature.Params, signature.Result) {
+			if !t.valid() {
+				o.err = fmt.Errorf("function %s() has invalid type %s in its signature", name, t)
+				return
+			}
+		}
+		if o.functions == nil {
+			o.functions = map[string]*function{}
+		}
+		o.functions[name] = &function{
+			params: append([]FunctionType{}, signature.Params...),
+			result: signature.Result,
+			call:   ignoringEvaluation(impl),
+		}
+	}
+}
+
+// function looks up a filter function by name.
+func (o *options) function(name string) (*function, bool) {
+	if f, ok := predefinedFunction(name); ok {
+		return f, true
+	}
+	f, ok := o.functions[name]
+	return f, ok
+}
+
+// predefinedFunction looks up a filter function, which is not defined by WithFunction, by name.
+func predefinedFunction(name string) (*function, bool) {
+	if f, ok := standardFunctions[name]; ok {
+		return f, true
+	}
+	f, ok := yamlFunctions[name]
+	return f, ok
+}
+
+// lengthFunction returns the number of characters in a string, items in a sequence, or entries in a mapping, or
+// Nothing for any other value.
+func lengthFunction(args []FunctionValue) FunctionValue {
+	v := args[0].Value
+	if v == nil {
+		return FunctionValue{}
+	}
+	var n int
+	switch v = dealias(v); jsonTypeOf(v) {
+	case jsonString:
+		n = utf8.RuneCountInString(v.Value)
+	case jsonArray:
+		n = len(v.Content)
+	case jsonObject:
+		n = len(v.Content) / 2
+	default:
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: intNode(n)}
+}
+
+// countFunction returns the number of nodes in a list of nodes.
+func countFunction(args []FunctionValue) FunctionValue {
+	return FunctionValue{Value: intNode(len(args[0].Nodes))}
+}
+
+// valueFunction returns the only node in a list of nodes, or Nothing if the list does not have exactly one node.
+func valueFunction(args []FunctionValue) FunctionValue {
+	if len(args[0].Nodes) != 1 {
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: args[0].Nodes[0]}
+}
+
+// anchorFunction returns the name of the anchor of a node, or Nothing if the node has no anchor.
+func anchorFunction(args []FunctionValue) FunctionValue {
+	v := args[0].Value
+	if v == nil || v.Anchor == "" {
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: strNode(v.Anchor)}
+}
+
+// aliasFunction returns the name of the anchor to which an alias node refers, or Nothing for any other node.
+func aliasFunction(args []FunctionValue) FunctionValue {
+	v := args[0].Value
+	if v == nil || v.Kind != yaml.AliasNode {
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: strNode(v.Value)}
+}
+
+// tagFunction returns the tag of a node in its short form, such as "!!str" or "!Ref", or Nothing if the node has no
+// tag.
+func tagFunction(args []FunctionValue) FunctionValue {
+	v := args[0].Value
+	if v == nil || v.ShortTag() == "" {
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: strNode(v.ShortTag())}
+}
+
+// kindNames are the names of the kinds of node returned by kind().
+var kindNames = map[yaml.Kind]string{
+	yaml.DocumentNode: "document",
+	yaml.SequenceNode: "sequence",
+	yaml.MappingNode:  "mapping",
+	yaml.ScalarNode:   "scalar",
+	yaml.AliasNode:    "alias",
+}
+
+// kindFunction returns the kind of a node: "document", "sequence", "mapping", "scalar", or "alias".
+func kindFunction(args []FunctionValue) FunctionValue {
+	v := args[0].Value
+	if v == nil {
+		return FunctionValue{}
+	}
+	name, ok := kindNames[v.Kind]
+	if !ok {
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: strNode(name)}
+}
+
+// commentFunction returns the comments of a node, including the comments of its key if the node is a mapping value,
+// or Nothing if there are no such comments.
+func commentFunction(args []FunctionValue) FunctionValue {
+	v := args[0].Value
+	if v == nil {
+		return FunctionValue{}
+	}
+	c := comments(v, args[0].key)
+	if c == "" {
+		return FunctionValue{}
+	}
+	return FunctionValue{Value: strNode(c)}
+}
+
+// comments returns the head, line, and foot comments of the given key, if it is not nil, followed by those of the
+// given node, separated by newlines. Each comment includes its leading "#".
+func comments(node, key *yaml.Node) string {
+	cs := []string{}
+	for _, n := range []*yaml.Node{key, node} {
+		if n == nil {
+			continue
+		}
+		for _, c := range []string{n.HeadComment, n.LineComment, n.FootComment} {
+			if c != "" {
+				cs = append(cs, c)
+			}
+		}
+	}
+	return strings.Join(cs, "\n")
+}
+
+// valueArgument returns the location's node as the value of a ValueType argument of a function.
+func (l *location) valueArgument() FunctionValue {
+	return FunctionValue{Value: l.node, key: l.key()}
+}
+
+// maxCachedIRegexps is the maximum number of regular expressions, other than a literal, cached by a call of match()
+// or search().
+const maxCachedIRegexps = 64
+
+// iRegexpMatcher implements a call of match() or search(). A literal regular expression is compiled when the call
+// is constructed and other regular expressions are cached, so that a regular expression is not compiled for each
+// node to which a filter is applied.
+type iRegexpMatcher struct {
+	entire  bool
+	literal *iRegexp // the literal regular expression of the call, or nil if it is not a literal
+
+	mu    sync.Mutex
+	cache map[string]*iRegexp
+}
+
+// iRegexp is an I-Regexp translated to a Go regular expression, which is compiled when it is first used.
+type iRegexp struct {
+	expr     string
+	size     int            // the size of the compiled program
+	compiled bool           // true if and only if re has been set
+	re       *regexp.Regexp // nil if the regular expression is invalid
+}
+
+// newIRegexpMatcher returns a matcher for a call of match(), if entire is true, or search(), if entire is false,
+// whose regular expression argument is the given literal, or nil if the argument is not a literal.
+func newIRegexpMatcher(literal *yaml.Node, entire bool) *iRegexpMatcher {
+	m := &iRegexpMatcher{
+		entire: entire,
+		cache:  map[string]*iRegexp{},
+	}
+	if literal != nil && jsonTypeOf(literal) == jsonString {
+		m.literal = newIRegexp(literal.Value, entire)
+		m.literal.compile()
+	}
+	return m
+}
+
+func newIRegexp(pattern string, entire bool) *iRegexp {
+	expr := translateIRegexp(pattern, entire)
+	return &iRegexp{
+		expr: expr,
+		size: regexpProgramSize(expr),
+	}
+}
+
+func (r *iRegexp) compile() {
+	r.re, _ = regexp.Compile(r.expr)
+	r.compiled = true
+}
+
+// match returns true if and only if both arguments are strings and the first argument matches the regular
+// expression (in the I-Regexp format of RFC 9485) given by the second argument, either in its entirety or, if
+// entire is false, in part.
+func (m *iRegexpMatcher) match(e *evaluation, args []FunctionValue) FunctionValue {
+	s, r := args[0].Value, args[1].Value
+	if s == nil || r == nil {
+		return FunctionValue{}
+	}
+	s, r = dealias(s), dealias(r)
+	if jsonTypeOf(s) != jsonString || jsonTypeOf(r) != jsonString {
+		return FunctionValue{}
+	}
+	re := m.regexp(e, r.Value)
+	if re == nil {
+		return FunctionValue{} // an invalid regular expression matches nothing
+	}
+	return FunctionValue{Logical: re.MatchString(s.Value)}
+}
+
+// regexp returns the compiled form of the given I-Regexp, or nil if it is invalid, after checking its size against
+// the evaluation's limits. A regular expression which exceeds the limits is not compiled.
+func (m *iRegexpMatcher) regexp(e *evaluation, pattern string) *regexp.Regexp {
+	if m.literal != nil {
+		e.checkRegexpSize(m.literal.size)
+		return m.literal.re
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.cache[pattern]
+	if !ok {
+		if len(m.cache) >= maxCachedIRegexps {
+			m.cache = map[string]*iRegexp{}
+		}
+		r = newIRegexp(pattern, m.entire)
+		m.cache[pattern] = r
+	}
+	e.checkRegexpSize(r.size)
+	if !r.compiled {
+		r.compile()
+	}
+	return r.re
+}
+
+// translateIRegexp converts an I-Regexp to a Go regular expression. I-Regexp is essentially a subset of Go's syntax
+// except that "." does not match carriage return.
+func translateIRegexp(expr string, entire bool) string {
+	var b strings.Builder
+	if entire {
+		b.WriteString(`^(?:`)
+	}
+	escaped := false
+	inClass := false
+	for _, r := range expr {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '[':
+			inClass = true
+		case r == ']':
+			inClass = false
+		case r == '.' && !inClass:
+			b.WriteString(`[^\n\r]`)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if entire {
+		b.WriteString(`)$`)
+	}
+	return b.String()
+}
+
+func strNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: s}
+}
+
+func intNode(i int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: intTag, Value: strconv.Itoa(i)}
+}
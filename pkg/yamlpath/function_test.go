@@ -0,0 +1,263 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWithFunction(t *testing.T) {
+	input := `items:
+- name: foo
+  size: 2
+  tags: [a, b]
+- name: bar
+  size: 3
+  tags: []
+- name: baz
+  size: 4
+  tags: [c]
+`
+
+	upper := yamlpath.WithFunction("upper", yamlpath.FunctionSignature{
+		Params: []yamlpath.FunctionType{yamlpath.ValueType},
+		Result: yamlpath.ValueType,
+	}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+		s, ok := args[0].Scalar()
+		if !ok {
+			return yamlpath.FunctionValue{}
+		}
+		str, ok := s.(string)
+		if !ok {
+			return yamlpath.FunctionValue{}
+		}
+		return yamlpath.ScalarValue(strings.ToUpper(str))
+	})
+
+	isEven := yamlpath.WithFunction("is_even", yamlpath.FunctionSignature{
+		Params: []yamlpath.FunctionType{yamlpath.ValueType},
+		Result: yamlpath.LogicalType,
+	}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+		i, ok := args[0].Scalar()
+		if !ok {
+			return yamlpath.FunctionValue{}
+		}
+		n, ok := i.(int)
+		return yamlpath.FunctionValue{Logical: ok && n%2 == 0}
+	})
+
+	empty := yamlpath.WithFunction("empty", yamlpath.FunctionSignature{
+		Params: []yamlpath.FunctionType{yamlpath.NodesType},
+		Result: yamlpath.LogicalType,
+	}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+		return yamlpath.FunctionValue{Logical: len(args[0].Nodes) == 0}
+	})
+
+	not := yamlpath.WithFunction("not", yamlpath.FunctionSignature{
+		Params: []yamlpath.FunctionType{yamlpath.LogicalType},
+		Result: yamlpath.LogicalType,
+	}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+		return yamlpath.FunctionValue{Logical: !args[0].Logical}
+	})
+
+	always := yamlpath.WithFunction("always", yamlpath.FunctionSignature{
+		Result: yamlpath.LogicalType,
+	}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+		return yamlpath.FunctionValue{Logical: true}
+	})
+
+	cases := []struct {
+		name            string
+		path            string
+		options         []yamlpath.Option
+		expectedStrings []string
+		expectedPathErr string
+		focus           bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:            "value function",
+			path:            "$.items[?(upper(@.name) == 'BAR')].size",
+			options:         []yamlpath.Option{upper},
+			expectedStrings: []string{"3\n"},
+		},
+		{
+			name:            "value function with nothing",
+			path:            "$.items[?(upper(@.size) == 'BAR')].size",
+			options:         []yamlpath.Option{upper},
+			expectedStrings: []string{},
+		},
+		{
+			name:            "logical function",
+			path:            "$.items[?(is_even(@.size))].name",
+			options:         []yamlpath.Option{isEven},
+			expectedStrings: []string{"foo\n", "baz\n"},
+		},
+		{
+			name:            "nodes parameter",
+			path:            "$.items[?(empty(@.tags[*]))].name",
+			options:         []yamlpath.Option{empty},
+			expectedStrings: []string{"bar\n"},
+		},
+		{
+			name:            "logical parameter",
+			path:            "$.items[?(not(@.size > 2))].name",
+			options:         []yamlpath.Option{not},
+			expectedStrings: []string{"foo\n"},
+		},
+		{
+			name:            "function without arguments",
+			path:            "$.items[?(always())].size",
+			options:         []yamlpath.Option{always},
+			expectedStrings: []string{"2\n", "3\n", "4\n"},
+		},
+		{
+			name:            "several functions",
+			path:            "$.items[?(is_even(@.size) && upper(@.name) != 'FOO')].name",
+			options:         []yamlpath.Option{upper, isEven},
+			expectedStrings: []string{"baz\n"},
+		},
+		{
+			name:            "function in nested filter",
+			path:            "$[?(@.items[?(upper(@.name) == 'BAZ')])].items[0].name",
+			options:         []yamlpath.Option{upper},
+			expectedStrings: []string{"foo\n"},
+		},
+		{
+			name:            "RFC 9535 value function",
+			path:            "$.items[?upper(@.name) == 'BAR'].size",
+			options:         []yamlpath.Option{yamlpath.RFC9535, upper},
+			expectedStrings: []string{"3\n"},
+		},
+		{
+			name:            "RFC 9535 logical function with standard function",
+			path:            "$.items[?is_even(length(@.tags))].name",
+			options:         []yamlpath.Option{yamlpath.RFC9535, isEven},
+			expectedStrings: []string{"foo\n", "bar\n"},
+		},
+		{
+			name:            "undefined function",
+			path:            "$.items[?(upper(@.name) == 'BAR')]",
+			options:         []yamlpath.Option{isEven},
+			expectedPathErr: `unknown function upper() at position 15, following "[?(upper"`,
+		},
+		{
+			name:            "RFC 9535 undefined function",
+			path:            "$.items[?upper(@.name) == 'BAR']",
+			options:         []yamlpath.Option{yamlpath.RFC9535},
+			expectedPathErr: `unknown function upper() at position 15, following "upper("`,
+		},
+		{
+			name:            "result of value function must be compared",
+			path:            "$.items[?(upper(@.name))]",
+			options:         []yamlpath.Option{upper},
+			expectedPathErr: "result of function upper() must be compared",
+		},
+		{
+			name:            "logical function cannot be compared",
+			path:            "$.items[?(is_even(@.size) == true)]",
+			options:         []yamlpath.Option{isEven},
+			expectedPathErr: "result of function is_even() is of type LogicalType and cannot be compared",
+		},
+		{
+			name:            "wrong number of arguments",
+			path:            "$.items[?(always(@.size))]",
+			options:         []yamlpath.Option{always},
+			expectedPathErr: "function always() takes 0 argument(s)",
+		},
+		{
+			name:            "non-singular query for value parameter",
+			path:            "$.items[?(upper(@.*) == 'FOO')]",
+			options:         []yamlpath.Option{upper},
+			expectedPathErr: "argument 1 of function upper(): query must be singular",
+		},
+		{
+			name:            "invalid function name",
+			path:            "$",
+			options:         []yamlpath.Option{yamlpath.WithFunction("Upper", yamlpath.FunctionSignature{}, func([]yamlpath.FunctionValue) yamlpath.FunctionValue { return yamlpath.FunctionValue{} })},
+			expectedPathErr: `invalid function name "Upper"`,
+		},
+		{
+			name:            "standard function redefined",
+			path:            "$",
+			options:         []yamlpath.Option{yamlpath.WithFunction("length", yamlpath.FunctionSignature{}, func([]yamlpath.FunctionValue) yamlpath.FunctionValue { return yamlpath.FunctionValue{} })},
+			expectedPathErr: "function length() cannot be redefined",
+		},
+		{
+			name:            "YAML function redefined",
+			path:            "$",
+			options:         []yamlpath.Option{yamlpath.WithFunction("anchor", yamlpath.FunctionSignature{}, func([]yamlpath.FunctionValue) yamlpath.FunctionValue { return yamlpath.FunctionValue{} })},
+			expectedPathErr: "function anchor() cannot be redefined",
+		},
+		{
+			name:            "function without implementation",
+			path:            "$",
+			options:         []yamlpath.Option{yamlpath.WithFunction("f", yamlpath.FunctionSignature{}, nil)},
+			expectedPathErr: "function f() has no implementation",
+		},
+		{
+			name:            "function with invalid type",
+			path:            "$",
+			options:         []yamlpath.Option{yamlpath.WithFunction("f", yamlpath.FunctionSignature{Result: yamlpath.FunctionType(9)}, func([]yamlpath.FunctionValue) yamlpath.FunctionValue { return yamlpath.FunctionValue{} })},
+			expectedPathErr: "function f() has invalid type FunctionType(9) in its signature",
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			if tc.expectedPathErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedPathErr)
+				require.Nil(t, p)
+				return
+			}
+
+			var n yaml.Node
+			err = yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			actual, err := p.Find(&n)
+			require.NoError(t, err)
+
+			actualStrings := []string{}
+			for _, a := range actual {
+				var buf bytes.Buffer
+				e := yaml.NewEncoder(&buf)
+				e.SetIndent(2)
+
+				err = e.Encode(a)
+				require.NoError(t, err)
+				e.Close()
+				actualStrings = append(actualStrings, buf.String())
+			}
+
+			require.Equal(t, tc.expectedStrings, actualStrings)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
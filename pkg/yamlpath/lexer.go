@@ -53,6 +53,24 @@ const (
 	lexemeBracketPropertyName
 	lexemeArraySubscriptPropertyName
 	lexemeRecursiveFilterBegin
+	lexemeFilterFunctionName
+	lexemeFilterArgumentSeparator
+	lexemeTagSelector
+	lexemeFilterAdd
+	lexemeFilterSubtract
+	lexemeFilterMultiply
+	lexemeFilterDivide
+	lexemeFilterModulo
+	lexemeFilterUnaryMinus
+	lexemeParent
+	lexemeAncestors
+	lexemeFilterKey
+	lexemeFilterPropertyName
+	lexemeUnionBegin
+	lexemeUnionName
+	lexemeUnionSubscript
+	lexemeUnionSeparator
+	lexemeUnionEnd
 	lexemeEOF // lexing complete
 )
 
@@ -92,6 +110,25 @@ func (t lexemeType) isComparisonOrMatch() bool {
 	return false
 }
 
+// isOrdering returns true if and only if the lexeme type is one of the ordering operators <, <=, >, and >=.
+func (t lexemeType) isOrdering() bool {
+	switch t {
+	case lexemeFilterGreaterThan, lexemeFilterGreaterThanOrEqual, lexemeFilterLessThan, lexemeFilterLessThanOrEqual:
+		return true
+	}
+	return false
+}
+
+// isArithmetic returns true if and only if the lexeme type is a binary arithmetic operator or unary minus.
+func (t lexemeType) isArithmetic() bool {
+	switch t {
+	case lexemeFilterAdd, lexemeFilterSubtract, lexemeFilterMultiply, lexemeFilterDivide, lexemeFilterModulo,
+		lexemeFilterUnaryMinus:
+		return true
+	}
+	return false
+}
+
 // a lexeme is a token returned from the lexer
 type lexeme struct {
 	typ lexemeType
@@ -144,8 +181,15 @@ func (l lexeme) literalValue() typedValue {
 	}
 }
 
+// sanitiseRegularExpressionLiteral converts a regular expression literal, such as /a\/b/i, to a regular expression
+// which may be compiled, such as (?i)a/b.
 func sanitiseRegularExpressionLiteral(re string) string {
-	return strings.ReplaceAll(re[1:len(re)-1], `\/`, `/`)
+	flags := ""
+	if strings.HasSuffix(re, filterRegularExpressionIgnoreCase) {
+		re = strings.TrimSuffix(re, filterRegularExpressionIgnoreCase)
+		flags = "(?i)"
+	}
+	return flags + strings.ReplaceAll(re[1:len(re)-1], `\/`, `/`)
 }
 
 func (l lexeme) comparator() comparator {
@@ -158,16 +202,19 @@ type stateFn func(*lexer) stateFn
 
 // lexer holds the state of the scanner.
 type lexer struct {
-	name                  string      // name of the lexer, used only for error reports
-	input                 string      // the string being scanned
-	start                 int         // start position of this item
-	pos                   int         // current position in the input
-	width                 int         // width of last rune read from input
-	state                 stateFn     // lexer state
-	stack                 []stateFn   // lexer stack
-	items                 chan lexeme // channel of scanned lexemes
-	lastEmittedStart      int         // start position of last scanned lexeme
-	lastEmittedLexemeType lexemeType  // type of last emitted lexeme (or lexemEOF if no lexeme has been emitted)
+	name                  string       // name of the lexer, used only for error reports
+	input                 string       // the string being scanned
+	start                 int          // start position of this item
+	pos                   int          // current position in the input
+	width                 int          // width of last rune read from input
+	state                 stateFn      // lexer state
+	stack                 []stateFn    // lexer stack
+	items                 chan lexeme  // channel of scanned lexemes
+	lastEmittedStart      int          // start position of last scanned lexeme
+	lastEmittedLexemeType lexemeType   // type of last emitted lexeme (or lexemEOF if no lexeme has been emitted)
+	filterBrackets        []int        // the number of unclosed brackets in each enclosing filter, innermost last
+	opts                  *options     // options which determine, for example, the available filter functions
+	err                   *SyntaxError // the error reported by the last lexemeError, if any
 }
 
 // lex creates a new scanner for the input string.
@@ -179,6 +226,7 @@ func lex(name, input string) *lexer {
 		stack:                 make([]stateFn, 0),
 		items:                 make(chan lexeme, 2),
 		lastEmittedLexemeType: lexemeEOF,
+		opts:                  &options{},
 	}
 	return l
 }
@@ -379,6 +427,33 @@ func (l *lexer) emit(typ lexemeType) {
 	l.lastEmittedStart = l.start
 	l.start = l.pos
 	l.lastEmittedLexemeType = typ
+
+	if n := len(l.filterBrackets); n > 0 {
+		switch typ {
+		case lexemeFilterOpenBracket:
+			l.filterBrackets[n-1]++
+		case lexemeFilterCloseBracket:
+			l.filterBrackets[n-1]--
+		}
+	}
+}
+
+// beginFilter records the start of a filter, so that the bracket which closes the filter can be recognised.
+func (l *lexer) beginFilter() {
+	l.filterBrackets = append(l.filterBrackets, 0)
+}
+
+// endFilter records the end of the innermost filter.
+func (l *lexer) endFilter() {
+	if n := len(l.filterBrackets); n > 0 {
+		l.filterBrackets = l.filterBrackets[:n-1]
+	}
+}
+
+// closesFilter returns true if and only if the input continues with the bracket which closes the innermost filter.
+func (l *lexer) closesFilter() bool {
+	n := len(l.filterBrackets)
+	return n > 0 && l.filterBrackets[n-1] == 0 && l.hasPrefix(filterCloseBracket)
 }
 
 // value returns the portion of the current lexeme scanned so far
@@ -411,18 +486,32 @@ func (l *lexer) hasPrefix(p string) bool {
 
 // errorf returns an error lexeme with context and terminates the scan
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
+	return l.errorfExpecting(nil, format, args...)
+}
+
+// errorfExpecting is like errorf but also records the tokens which would have been valid at the current position.
+func (l *lexer) errorfExpecting(expected []string, format string, args ...interface{}) stateFn {
+	msg := fmt.Sprintf("%s at position %d, following %q", fmt.Sprintf(format, args...), l.pos, l.context())
+	l.err = newSyntaxError(msg, l.input, l.pos, l.start, l.pos, expected)
 	l.items <- lexeme{
 		typ: lexemeError,
-		val: fmt.Sprintf("%s at position %d, following %q", fmt.Sprintf(format, args...), l.pos, l.context()),
+		val: msg,
 	}
 	return nil
 }
 
-// rawErrorf returns an error lexeme with no context and terminates the scan
-func (l *lexer) rawErrorf(format string, args ...interface{}) stateFn {
+// rawErrorf returns an error lexeme with no context, for an error detected at the given position, and terminates
+// the scan
+func (l *lexer) rawErrorf(pos int, format string, args ...interface{}) stateFn {
+	msg := fmt.Sprintf(format, args...)
+	if pos < l.pos { // the error is in the token starting at pos
+		l.err = newSyntaxError(msg, l.input, pos, pos, l.pos, nil)
+	} else {
+		l.err = newSyntaxError(msg, l.input, pos, l.start, pos, nil)
+	}
 	l.items <- lexeme{
 		typ: lexemeError,
-		val: fmt.Sprintf(format, args...),
+		val: msg,
 	}
 	return nil
 }
@@ -435,11 +524,15 @@ const (
 	bracketQuote                            string = "['"
 	bracketDoubleQuote                      string = `["`
 	filterBegin                             string = "[?("
+	unionFilterBegin                        string = "?("
+	unionSeparator                          string = ","
 	filterEnd                               string = ")]"
 	filterOpenBracket                       string = "("
 	filterCloseBracket                      string = ")"
 	filterNot                               string = "!"
+	filterArgumentSeparator                 string = ","
 	filterAt                                string = "@"
+	filterKey                               string = "@~"
 	filterConjunction                       string = "&&"
 	filterDisjunction                       string = "||"
 	filterEquality                          string = "=="
@@ -449,10 +542,29 @@ const (
 	filterStringLiteralAlternateDelimiter   string = `"`
 	filterRegularExpressionLiteralDelimiter string = "/"
 	filterRegularExpressionEscape           string = `\`
+	filterRegularExpressionIgnoreCase       string = "i"
 	recursiveDescent                        string = ".."
 	propertyName                            string = "~"
+	tagSelectorBegin                        string = "[!"
+	parent                                  string = "^"
+	ancestors                               string = "^*"
+	filterAdd                               string = "+"
+	filterSubtract                          string = "-"
+	filterMultiply                          string = "*"
+	filterDivide                            string = "/"
+	filterModulo                            string = "%"
 )
 
+// arithmeticOperatorLexeme maps the binary arithmetic operators to their lexeme types. A filterSubtract which
+// begins a filter term is lexed as unary minus instead.
+var arithmeticOperatorLexeme = map[string]lexemeType{
+	filterAdd:      lexemeFilterAdd,
+	filterSubtract: lexemeFilterSubtract,
+	filterMultiply: lexemeFilterMultiply,
+	filterDivide:   lexemeFilterDivide,
+	filterModulo:   lexemeFilterModulo,
+}
+
 var orderingOperators []orderingOperator
 
 func init() {
@@ -500,7 +612,7 @@ func consumedEscapedString(l *lexer, quote string) bool {
 			return false
 		default:
 			if l.next() == eof {
-				l.errorf("unmatched %s", enquote(quote))
+				l.errorfExpecting([]string{quote}, "unmatched %s", enquote(quote))
 				return false
 			}
 		}
@@ -512,6 +624,9 @@ func lexSubPath(l *lexer) stateFn {
 	case l.hasPrefix(")"):
 		return l.pop()
 
+	case l.hasPrefix(filterArgumentSeparator) && !l.emptyStack():
+		return l.pop()
+
 	case l.empty():
 		if !l.emptyStack() {
 			return l.pop()
@@ -524,7 +639,7 @@ func lexSubPath(l *lexer) stateFn {
 		childName := false
 		for {
 			le := l.next()
-			if le == '.' || le == '[' || le == eof {
+			if le == '.' || le == '[' || le == '^' || le == '~' || le == eof || (!l.emptyStack() && isFilterDelimiter(le)) {
 				l.backup()
 				break
 			}
@@ -533,14 +648,21 @@ func lexSubPath(l *lexer) stateFn {
 		if !childName && !l.peeked(leftBracket) {
 			return l.errorf("child name or array access or filter missing after recursive descent")
 		}
+		if childName {
+			l.consumed(propertyName) // a trailing ~ selects the property names of the descendants
+		}
 		l.emit(lexemeRecursiveDescent)
+		if !l.emptyStack() && isFilterDelimiter(l.peek()) {
+			return l.pop()
+		}
 		return lexSubPath
 
 	case l.consumed(dot):
 		childName := false
 		for {
 			le := l.next()
-			if le == '.' || le == '[' || le == ')' || le == ' ' || le == '&' || le == '|' || le == '=' || le == '!' || le == '>' || le == '<' || le == '~' || le == eof {
+			if le == '.' || le == '[' || le == ')' || le == ' ' || le == '&' || le == '|' || le == '=' || le == '!' || le == '>' || le == '<' || le == '~' || le == '^' || le == eof ||
+				(le == ',' && !l.emptyStack()) {
 				l.backup()
 				break
 			}
@@ -550,11 +672,8 @@ func lexSubPath(l *lexer) stateFn {
 			return l.errorf("child name missing")
 		}
 		if l.consumed(propertyName) {
-			if l.peek() != eof {
-				return l.errorf("property name operator may only be used on last child in path")
-			}
 			l.emit(lexemePropertyName)
-			return lexSubPath
+			return lexOptionalArrayIndex
 		}
 
 		l.emit(lexemeDotChild)
@@ -571,25 +690,27 @@ func lexSubPath(l *lexer) stateFn {
 				return nil
 			}
 			if !l.consumed(quote) {
-				return l.errorf(`missing %s`, enquote(quote))
+				return l.errorfExpecting([]string{quote}, `missing %s`, enquote(quote))
 			}
 			if l.consumedWhitespaced(",") {
 				if !l.peekedWhitespaced("'") && !l.peekedWhitespaced(`"`) {
-					return l.errorf(`missing %s or %s`, enquote("'"), enquote(`"`))
+					if l.peekedWhitespaced(rightBracket) || l.empty() {
+						return l.errorfExpecting([]string{"'", `"`}, `missing %s or %s`, enquote("'"), enquote(`"`))
+					}
+					// a union of names and other selectors
+					l.pos = l.start
+					return lexUnion
 				}
 			} else {
 				break
 			}
 		}
 		if !l.consumedWhitespaced("]") {
-			return l.errorf(`missing "]" or ","`)
+			return l.errorfExpecting([]string{rightBracket, ","}, `missing "]" or ","`)
 		}
 		if l.consumed(propertyName) {
 			l.emit(lexemeBracketPropertyName)
-			if l.peek() != eof {
-				return l.errorf("property name operator may only be used on last child in path")
-			}
-			return lexSubPath
+			return lexOptionalArrayIndex
 		}
 
 		l.emit(lexemeBracketChild)
@@ -603,8 +724,26 @@ func lexSubPath(l *lexer) stateFn {
 			l.emit(lexemeFilterBegin)
 		}
 		l.push(lexFilterEnd)
+		l.beginFilter()
 		return lexFilterExprInitial
 
+	case l.consumed(ancestors):
+		l.emit(lexemeAncestors)
+		return lexOptionalArrayIndex
+
+	case l.consumed(parent):
+		l.emit(lexemeParent)
+		return lexOptionalArrayIndex
+
+	case l.consumed(tagSelectorBegin):
+		for !l.consumed(rightBracket) {
+			if l.next() == eof {
+				return l.errorfExpecting([]string{rightBracket}, "unmatched %s", leftBracket)
+			}
+		}
+		l.emit(lexemeTagSelector)
+		return lexOptionalArrayIndex
+
 	case l.peeked(leftBracket):
 		return lexOptionalArrayIndex
 
@@ -612,7 +751,7 @@ func lexSubPath(l *lexer) stateFn {
 		childName := false
 		for {
 			le := l.next()
-			if le == '.' || le == '[' || le == ']' || le == ')' || le == ' ' || le == '&' || le == '|' || le == '=' || le == '!' || le == '>' || le == '<' || le == '~' || le == eof {
+			if le == '.' || le == '[' || le == ']' || le == ')' || le == ' ' || le == '&' || le == '|' || le == '=' || le == '!' || le == '>' || le == '<' || le == '~' || le == '^' || le == eof {
 				l.backup()
 				break
 			}
@@ -622,11 +761,8 @@ func lexSubPath(l *lexer) stateFn {
 			return l.errorf("child name missing")
 		}
 		if l.consumed(propertyName) {
-			if l.peek() != eof {
-				return l.errorf("property name operator may only be used on last child in path")
-			}
 			l.emit(lexemePropertyName)
-			return lexSubPath
+			return lexOptionalArrayIndex
 		}
 		l.emit(lexemeUndottedChild)
 
@@ -638,35 +774,40 @@ func lexSubPath(l *lexer) stateFn {
 }
 
 func lexOptionalArrayIndex(l *lexer) stateFn {
-	if l.consumed(leftBracket, bracketQuote, bracketDoubleQuote, filterBegin) {
+	start := l.pos
+	if l.consumed(leftBracket, bracketQuote, bracketDoubleQuote, filterBegin, tagSelectorBegin) {
 		subscript := false
 		for {
 			if l.consumed(rightBracket) {
 				break
 			}
-			if l.next() == eof {
-				return l.errorf("unmatched %s", leftBracket)
+			switch l.next() {
+			case eof:
+				return l.errorfExpecting([]string{rightBracket}, "unmatched %s", leftBracket)
+			case '\'', '"', '?': // a union including a name or a filter
+				l.pos = start
+				return lexUnion
 			}
 			subscript = true
 		}
 		if !subscript {
-			return l.rawErrorf("subscript missing from %s%s before position %d", leftBracket, rightBracket, l.pos)
+			return l.rawErrorf(l.pos, "subscript missing from %s%s before position %d", leftBracket, rightBracket, l.pos)
+		}
+		if isWildcardUnion(l.input[start:l.pos]) {
+			l.pos = start
+			return lexUnion
 		}
 		if !validateArrayIndex(l) {
 			return nil
 		}
 		if l.consumed(propertyName) {
-			if l.peek() != eof {
-				return l.errorf("property name operator can only be used on last item in path")
-			}
 			subscript := l.value()
 			index := strings.TrimSuffix(strings.TrimPrefix(subscript, leftBracket), rightBracket+propertyName)
 			if index != "*" {
 				return l.errorf("property name operator can only be used on map nodes")
 			}
 			l.emit(lexemeArraySubscriptPropertyName)
-			return lexSubPath
-
+			return lexOptionalArrayIndex
 		}
 		l.emit(lexemeArraySubscript)
 	}
@@ -682,6 +823,103 @@ func lexOptionalArrayIndex(l *lexer) stateFn {
 	return lexSubPath
 }
 
+// isWildcardUnion returns true if and only if the given array subscript is a union which includes a wildcard.
+func isWildcardUnion(subscript string) bool {
+	members := strings.Split(strings.TrimSuffix(strings.TrimPrefix(subscript, leftBracket), rightBracket), unionSeparator)
+	if len(members) < 2 {
+		return false
+	}
+	for _, m := range members {
+		if strings.TrimSpace(m) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// lexUnion lexes a bracketed union of selectors of mixed kinds, such as `[0,'name',2:4,?(@.x)]`, each of which is a
+// quoted name, an index, a slice, a wildcard, or a filter.
+func lexUnion(l *lexer) stateFn {
+	l.consumedWhitespaced(leftBracket)
+	l.emit(lexemeUnionBegin)
+	return lexUnionMember
+}
+
+func lexUnionMember(l *lexer) stateFn {
+	l.stripWhitespace()
+	switch {
+	case l.hasPrefix("'") || l.hasPrefix(`"`):
+		quote := string(l.next())
+		if !consumedEscapedString(l, quote) {
+			return nil
+		}
+		l.consume(quote)
+		l.emit(lexemeUnionName)
+		return lexUnionMemberEnd
+
+	case l.consumed(unionFilterBegin):
+		l.emit(lexemeFilterBegin)
+		l.push(lexUnionFilterEnd)
+		l.beginFilter()
+		return lexFilterExprInitial
+
+	case l.hasPrefix("?"):
+		return l.errorfExpecting([]string{unionFilterBegin}, "missing %s after ?", filterOpenBracket)
+	}
+
+	for {
+		r := l.next()
+		if r == eof {
+			return l.errorfExpecting([]string{rightBracket}, "unmatched %s", leftBracket)
+		}
+		if r == ',' || r == ']' {
+			l.backup()
+			break
+		}
+	}
+	member := strings.TrimSpace(l.value())
+	if member == "" {
+		return l.errorf("union member missing")
+	}
+	if member != "*" {
+		if _, err := parseSubscript(member); err != nil {
+			return l.errorf("invalid union member %s: %s", member, err)
+		}
+	}
+	l.emit(lexemeUnionSubscript)
+	return lexUnionMemberEnd
+}
+
+func lexUnionMemberEnd(l *lexer) stateFn {
+	l.stripWhitespace()
+	switch {
+	case l.consumed(unionSeparator):
+		l.emit(lexemeUnionSeparator)
+		return lexUnionMember
+
+	case l.consumed(rightBracket):
+		l.emit(lexemeUnionEnd)
+		if l.peeked(propertyName) {
+			return l.errorf("property name operator cannot be used on a union of mixed selectors")
+		}
+		return lexOptionalArrayIndex
+	}
+	return l.errorfExpecting([]string{rightBracket, unionSeparator}, `missing "]" or ","`)
+}
+
+// lexUnionFilterEnd lexes the bracket which closes a filter in a union.
+func lexUnionFilterEnd(l *lexer) stateFn {
+	if !l.consumed(filterCloseBracket) {
+		return l.errorf("invalid filter syntax")
+	}
+	if l.lastEmittedLexemeType == lexemeFilterBegin {
+		return l.errorf("missing filter")
+	}
+	l.emit(lexemeFilterEnd)
+	l.endFilter()
+	return lexUnionMemberEnd
+}
+
 func enquote(quote string) string {
 	switch quote {
 	case "'":
@@ -698,6 +936,15 @@ func enquote(quote string) string {
 func lexFilterExprInitial(l *lexer) stateFn {
 	l.stripWhitespace()
 
+	if nextState, present := lexFunction(l); present {
+		return nextState
+	}
+
+	if l.peekedUnaryMinus() {
+		l.push(lexFilterExpr)
+		return lexFilterTerm
+	}
+
 	if nextState, present := lexNumericLiteral(l, lexFilterExpr); present {
 		return nextState
 	}
@@ -727,9 +974,14 @@ func lexFilterExprInitial(l *lexer) stateFn {
 		l.emit(lexemeFilterNot)
 		return lexFilterExprInitial
 
+	case l.consumed(filterKey):
+		l.emit(lexemeFilterKey)
+		return lexFilterExpr
+
 	case l.consumed(filterAt):
 		l.emit(lexemeFilterAt)
-		if l.peekedWhitespaced("=") || l.peekedWhitespaced("!") || l.peekedWhitespaced(">") || l.peekedWhitespaced("<") {
+		if l.peekedWhitespaced("=") || l.peekedWhitespaced("!") || l.peekedWhitespaced(">") || l.peekedWhitespaced("<") ||
+			l.peekedWhitespaced(filterArgumentSeparator) || l.peekedArithmeticOperator() {
 			return lexFilterExpr
 		}
 		l.push(lexFilterExpr)
@@ -764,15 +1016,22 @@ func lexFilterExpr(l *lexer) stateFn {
 
 	switch {
 	case l.empty():
-		return l.errorf("missing end of filter")
+		return l.errorfExpecting([]string{filterEnd}, "missing end of filter")
 
 	case l.hasPrefix(filterEnd): // this will be consumed by the popped state function
 		return l.pop()
 
+	case l.closesFilter(): // this will be consumed by the popped state function
+		return l.pop()
+
 	case l.consumed(filterCloseBracket):
 		l.emit(lexemeFilterCloseBracket)
 		return l.pop()
 
+	case l.consumed(filterArgumentSeparator):
+		l.emit(lexemeFilterArgumentSeparator)
+		return lexFilterExprInitial
+
 	case l.consumed(filterConjunction):
 		l.emit(lexemeFilterAnd)
 		l.stripWhitespace()
@@ -811,16 +1070,43 @@ func lexFilterExpr(l *lexer) stateFn {
 		}
 	}
 
+	for o, typ := range arithmeticOperatorLexeme {
+		if l.consumed(o) {
+			l.emit(typ)
+			l.push(lexFilterExpr)
+			return lexFilterTerm
+		}
+	}
+
 	return l.errorf("invalid filter expression")
 }
 
 func lexFilterTerm(l *lexer) stateFn {
 	l.stripWhitespace()
 
+	if l.peekedUnaryMinus() {
+		l.consume(filterSubtract)
+		l.emit(lexemeFilterUnaryMinus)
+		return lexFilterTerm
+	}
+
+	if l.consumed(filterOpenBracket) {
+		l.emit(lexemeFilterOpenBracket)
+		l.push(lexFilterExpr)
+		return lexFilterExprInitial
+	}
+
+	if l.consumed(filterKey) {
+		l.emit(lexemeFilterKey)
+		return lexFilterExpr
+	}
+
 	if l.consumed(filterAt) {
 		l.emit(lexemeFilterAt)
 
-		if l.peekedWhitespaced("|") || l.peekedWhitespaced("&") || l.peekedWhitespaced(")") {
+		if l.peekedWhitespaced("|") || l.peekedWhitespaced("&") || l.peekedWhitespaced(")") || l.peekedWhitespaced(filterArgumentSeparator) ||
+			l.peekedWhitespaced("=") || l.peekedWhitespaced("!") || l.peekedWhitespaced(">") || l.peekedWhitespaced("<") ||
+			l.peekedArithmeticOperator() {
 			if l.emptyStack() {
 				return l.errorf("invalid character %q", l.peek())
 			}
@@ -834,6 +1120,10 @@ func lexFilterTerm(l *lexer) stateFn {
 		return lexSubPath
 	}
 
+	if nextState, present := lexFunction(l); present {
+		return nextState
+	}
+
 	if nextState, present := lexNumericLiteral(l, lexFilterExpr); present {
 		return nextState
 	}
@@ -853,6 +1143,25 @@ func lexFilterTerm(l *lexer) stateFn {
 	return l.errorf("invalid filter term")
 }
 
+// peekedUnaryMinus returns true if and only if the input continues with a minus sign which is not part of a numeric
+// literal.
+func (l *lexer) peekedUnaryMinus() bool {
+	rest := l.input[l.pos:]
+	return strings.HasPrefix(rest, filterSubtract) &&
+		(len(rest) == len(filterSubtract) || !strings.ContainsRune(".0123456789", rune(rest[len(filterSubtract)])))
+}
+
+// peekedArithmeticOperator returns true if and only if the input continues, after any whitespace, with a binary
+// arithmetic operator.
+func (l *lexer) peekedArithmeticOperator() bool {
+	for o := range arithmeticOperatorLexeme {
+		if l.peekedWhitespaced(o) {
+			return true
+		}
+	}
+	return false
+}
+
 func lexFilterEnd(l *lexer) stateFn {
 	if l.hasPrefix(filterEnd) {
 		if l.lastEmittedLexemeType == lexemeFilterBegin {
@@ -860,22 +1169,80 @@ func lexFilterEnd(l *lexer) stateFn {
 		}
 		l.consume(filterEnd)
 		l.emit(lexemeFilterEnd)
+		l.endFilter()
+		if l.consumed(propertyName) {
+			l.emit(lexemeFilterPropertyName)
+			return lexOptionalArrayIndex
+		}
 		return lexSubPath
 	}
 
+	if l.peekedWhitespaced(filterCloseBracket, unionSeparator) { // a filter which begins a union
+		if l.lastEmittedLexemeType == lexemeFilterBegin {
+			return l.errorf("missing filter")
+		}
+		l.consume(filterCloseBracket)
+		l.emit(lexemeFilterEnd)
+		l.endFilter()
+		return lexUnionMemberEnd
+	}
+
 	return l.errorf("invalid filter syntax")
 }
 
 func validateArrayIndex(l *lexer) bool {
 	subscript := l.value()
 	index := strings.TrimSuffix(strings.TrimPrefix(subscript, leftBracket), rightBracket)
-	if _, err := slice(index, 0); err != nil {
-		l.rawErrorf("invalid array index %s before position %d: %s", subscript, l.pos, err)
+	if _, err := parseSubscripts(index); err != nil {
+		l.rawErrorf(l.pos, "invalid array index %s before position %d: %s", subscript, l.pos, err)
 		return false
 	}
 	return true
 }
 
+// isFilterDelimiter returns true if and only if the given rune ends a path inside a filter.
+func isFilterDelimiter(r rune) bool {
+	return strings.ContainsRune(") &|=!><,", r)
+}
+
+// lexFunction lexes the name of a function extension, if present, followed by the opening bracket of the function's
+// arguments. Any arguments are lexed as filter expressions separated by commas.
+func lexFunction(l *lexer) (stateFn, bool) {
+	pos := l.pos
+	if r := l.peek(); r < 'a' || r > 'z' {
+		return nil, false
+	}
+	for {
+		r := l.next()
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			l.backup()
+			break
+		}
+	}
+	if !l.hasPrefix(filterOpenBracket) {
+		l.pos = pos
+		return nil, false
+	}
+	if _, ok := l.opts.function(l.value()); !ok {
+		return l.errorf("unknown function %s()", l.value()), true
+	}
+	l.emit(lexemeFilterFunctionName)
+	l.consume(filterOpenBracket)
+	l.emit(lexemeFilterOpenBracket)
+	return lexFilterArguments, true
+}
+
+// lexFilterArguments lexes the arguments, if any, of a function extension.
+func lexFilterArguments(l *lexer) stateFn {
+	l.stripWhitespace()
+	if l.consumed(filterCloseBracket) {
+		l.emit(lexemeFilterCloseBracket)
+		return lexFilterExpr
+	}
+	l.push(lexFilterExpr)
+	return lexFilterExprInitial
+}
+
 func lexNumericLiteral(l *lexer, nextState stateFn) (stateFn, bool) {
 	n := l.peek()
 	if n == '.' || n == '-' || (n >= '0' && n <= '9') {
@@ -896,7 +1263,7 @@ func lexNumericLiteral(l *lexer, nextState stateFn) (stateFn, bool) {
 			// validate float
 			if _, err := strconv.ParseFloat(l.value(), 64); err != nil {
 				err := err.(*strconv.NumError)
-				return l.rawErrorf("invalid float literal %q: %s before position %d", err.Num, err, l.pos), true
+				return l.rawErrorf(l.pos, "invalid float literal %q: %s before position %d", err.Num, err, l.pos), true
 			}
 			l.emit(lexemeFilterFloatLiteral)
 			return lexFilterExpr, true
@@ -904,7 +1271,7 @@ func lexNumericLiteral(l *lexer, nextState stateFn) (stateFn, bool) {
 		// validate integer
 		if _, err := strconv.Atoi(l.value()); err != nil {
 			err := err.(*strconv.NumError)
-			return l.rawErrorf("invalid integer literal %q: %s before position %d", err.Num, err, l.pos), true
+			return l.rawErrorf(l.pos, "invalid integer literal %q: %s before position %d", err.Num, err, l.pos), true
 		}
 		l.emit(lexemeFilterIntegerLiteral)
 		return lexFilterExpr, true
@@ -924,7 +1291,7 @@ func lexStringLiteral(l *lexer, nextState stateFn) (stateFn, bool) {
 		context := l.context()
 		for {
 			if l.next() == eof {
-				return l.rawErrorf(`unmatched string delimiter %s at position %d, following %q`, quote, pos, context), true
+				return l.rawErrorf(pos, `unmatched string delimiter %s at position %d, following %q`, quote, pos, context), true
 			}
 			if l.hasPrefix(quote) {
 				break
@@ -966,17 +1333,9 @@ func init() {
 }
 
 func lexComparison(l *lexer, comparisonOperator orderingOperator) stateFn {
-	if l.lastEmittedLexemeType == lexemeFilterStringLiteral {
-		return l.errorf("strings cannot be compared using %s", comparisonOperator)
-	}
 	l.consume(comparisonOperator.String())
 	l.emit(comparisonOperatorLexeme[comparisonOperator])
 
-	l.stripWhitespace()
-	if l.hasPrefix(filterStringLiteralDelimiter) {
-		return l.errorf("strings cannot be compared using %s", comparisonOperator)
-	}
-
 	l.push(lexFilterExpr)
 	return lexFilterTerm
 }
@@ -990,7 +1349,7 @@ func lexRegularExpressionLiteral(l *lexer, nextState stateFn) stateFn {
 	escape := false
 	for {
 		if l.next() == eof {
-			return l.rawErrorf(`unmatched regular expression delimiter %s at position %d, following %q`, filterRegularExpressionLiteralDelimiter, pos, context)
+			return l.rawErrorf(pos, `unmatched regular expression delimiter %s at position %d, following %q`, filterRegularExpressionLiteralDelimiter, pos, context)
 		}
 		if !escape && l.hasPrefix(filterRegularExpressionLiteralDelimiter) {
 			break
@@ -1002,8 +1361,9 @@ func lexRegularExpressionLiteral(l *lexer, nextState stateFn) stateFn {
 		}
 	}
 	l.next()
+	l.consumed(filterRegularExpressionIgnoreCase)
 	if _, err := regexp.Compile(sanitiseRegularExpressionLiteral(l.value())); err != nil {
-		return l.rawErrorf(`invalid regular expression at position %d, following %q: %s`, pos, context, err)
+		return l.rawErrorf(pos, `invalid regular expression at position %d, following %q: %s`, pos, context, err)
 	}
 	l.emit(lexemeFilterRegularExpressionLiteral)
 
@@ -16,6 +16,7 @@ func TestLexer(t *testing.T) {
 	cases := []struct {
 		name     string
 		path     string
+		options  []Option
 		expected []lexeme
 		focus    bool // if true, run only tests with focus set to true
 	}{
@@ -137,7 +138,8 @@ func TestLexer(t *testing.T) {
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeDotChild, val: ".child"},
-				{typ: lexemeError, val: "invalid array index [1:2:0] before position 14: array index step value must be non-zero"},
+				{typ: lexemeArraySubscript, val: "[1:2:0]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -389,7 +391,9 @@ func TestLexer(t *testing.T) {
 			path: "$.child~.test",
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
-				{typ: lexemeError, val: `property name operator may only be used on last child in path at position 8, following "$.child~"`},
+				{typ: lexemePropertyName, val: ".child~"},
+				{typ: lexemeDotChild, val: ".test"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -397,7 +401,9 @@ func TestLexer(t *testing.T) {
 			path: "child~.test",
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
-				{typ: lexemeError, val: `property name operator may only be used on last child in path at position 6, following "child~"`},
+				{typ: lexemePropertyName, val: "child~"},
+				{typ: lexemeDotChild, val: ".test"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -444,7 +450,7 @@ func TestLexer(t *testing.T) {
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeDotChild, val: ".child"},
-				{typ: lexemeError, val: "invalid array index [1:2:0] before position 14: array index step value must be non-zero"},
+				{typ: lexemeError, val: `property name operator can only be used on map nodes at position 15, following ".child[1:2:0]~"`},
 			},
 		},
 		{
@@ -590,7 +596,9 @@ func TestLexer(t *testing.T) {
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeBracketChild, val: "['child']"},
-				{typ: lexemeError, val: `property name operator can only be used on last item in path at position 14, following "['child'][*]~"`},
+				{typ: lexemeArraySubscriptPropertyName, val: "[*]~"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -653,7 +661,7 @@ func TestLexer(t *testing.T) {
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeBracketPropertyName, val: "['child']~"},
-				{typ: lexemeError, val: `property name operator may only be used on last child in path at position 11, following "['child']~"`},
+				{typ: lexemeError, val: `invalid character ' ' at position 11, following "['child']~"`},
 			},
 		},
 		{
@@ -683,6 +691,143 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeIdentity, val: ""},
 			},
 		},
+		{
+			name: "mixed union",
+			path: "$[0, 'name', 2:4, ?(@.x)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionSubscript, val: "0"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionName, val: "'name'"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionSubscript, val: "2:4"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeFilterBegin, val: "?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".x"},
+				{typ: lexemeFilterEnd, val: ")"},
+				{typ: lexemeUnionEnd, val: "]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "union beginning with a name",
+			path: `$.a["b",*].c`,
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionName, val: `"b"`},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionSubscript, val: "*"},
+				{typ: lexemeUnionEnd, val: "]"},
+				{typ: lexemeDotChild, val: ".c"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "union beginning with a filter",
+			path: "$[?((@.x) || length(@.y) > 1) ,0]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".x"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterOr, val: "||"},
+				{typ: lexemeFilterFunctionName, val: "length"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".y"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterGreaterThan, val: ">"},
+				{typ: lexemeFilterIntegerLiteral, val: "1"},
+				{typ: lexemeFilterEnd, val: ")"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionSubscript, val: "0"},
+				{typ: lexemeUnionEnd, val: "]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "union with wildcard",
+			path: "$[*, 1]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionSubscript, val: "*"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionSubscript, val: "1"},
+				{typ: lexemeUnionEnd, val: "]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "union in filter",
+			path: "$[?(@[0,'a'])]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionSubscript, val: "0"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionName, val: "'a'"},
+				{typ: lexemeUnionEnd, val: "]"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "union with invalid member",
+			path: "$['a',b]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionName, val: "'a'"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeError, val: `invalid union member b: non-integer array index at position 7, following ",b"`},
+			},
+		},
+		{
+			name: "union with missing filter",
+			path: "$[0,?()]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionSubscript, val: "0"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeFilterBegin, val: "?("},
+				{typ: lexemeError, val: `missing filter at position 7, following "?()"`},
+			},
+		},
+		{
+			name: "unmatched union",
+			path: "$[0,'a'",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionSubscript, val: "0"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionName, val: "'a'"},
+				{typ: lexemeError, val: `missing "]" or "," at position 7, following "'a'"`},
+			},
+		},
+		{
+			name: "property name of union",
+			path: "$[0,'a']~",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeUnionBegin, val: "["},
+				{typ: lexemeUnionSubscript, val: "0"},
+				{typ: lexemeUnionSeparator, val: ","},
+				{typ: lexemeUnionName, val: "'a'"},
+				{typ: lexemeUnionEnd, val: "]"},
+				{typ: lexemeError, val: `property name operator cannot be used on a union of mixed selectors at position 8, following "]"`},
+			},
+		},
 		{
 			name: "bracket child with malformed array subscript",
 			path: "$['child'][1:2:3:4]",
@@ -829,6 +974,99 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeIdentity, val: ""},
 			},
 		},
+		{
+			name: "recursive descent with tag selector",
+			path: "$..[!Ref]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeRecursiveDescent, val: ".."},
+				{typ: lexemeTagSelector, val: "[!Ref]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "tag selector after dot child",
+			path: "$.a[!!str].b",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeTagSelector, val: "[!!str]"},
+				{typ: lexemeDotChild, val: ".b"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "recursive descent with property name",
+			path: "$..a~[?(@=='b')]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeRecursiveDescent, val: "..a~"},
+				{typ: lexemeRecursiveFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterStringLiteral, val: "'b'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "property names in filter",
+			path: "$[?(@.a~=='a' && @[*]~ && @['b']~)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemePropertyName, val: ".a~"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterStringLiteral, val: "'a'"},
+				{typ: lexemeFilterAnd, val: "&&"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeArraySubscriptPropertyName, val: "[*]~"},
+				{typ: lexemeFilterAnd, val: "&&"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeBracketPropertyName, val: "['b']~"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "parent and ancestors",
+			path: "$.a^['b']^*[0]^",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeParent, val: "^"},
+				{typ: lexemeBracketChild, val: "['b']"},
+				{typ: lexemeAncestors, val: "^*"},
+				{typ: lexemeArraySubscript, val: "[0]"},
+				{typ: lexemeParent, val: "^"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "parent in filter",
+			path: "$[?(@^.a==1)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeParent, val: "^"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterIntegerLiteral, val: "1"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "unmatched tag selector",
+			path: "$.a[!Ref",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeError, val: `unmatched [ at position 8, following ".a[!Ref"`},
+			},
+		},
 		{
 			name: "recursive descent with bracket child",
 			path: "$..['child']",
@@ -1106,7 +1344,7 @@ func TestLexer(t *testing.T) {
 			},
 		},
 		{
-			name: "filter integer equality with invalid literal",
+			name: "filter integer equality with unary minus and no operand",
 			path: "$[?(@.child==-)]",
 			expected: []lexeme{
 				{typ: lexemeRoot, val: "$"},
@@ -1114,7 +1352,8 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeFilterAt, val: "@"},
 				{typ: lexemeDotChild, val: ".child"},
 				{typ: lexemeFilterEquality, val: "=="},
-				{typ: lexemeError, val: `invalid integer literal "-": strconv.Atoi: parsing "-": invalid syntax before position 14`},
+				{typ: lexemeFilterUnaryMinus, val: "-"},
+				{typ: lexemeError, val: `invalid filter term at position 14, following "-"`},
 			},
 		},
 		{
@@ -1511,7 +1750,9 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeFilterAt, val: "@"},
 				{typ: lexemeDotChild, val: ".child"},
 				{typ: lexemeFilterGreaterThan, val: ">"},
-				{typ: lexemeError, val: `strings cannot be compared using > at position 12, following ">"`},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1521,7 +1762,11 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeFilterBegin, val: "[?("},
 				{typ: lexemeFilterStringLiteral, val: "'x'"},
-				{typ: lexemeError, val: `strings cannot be compared using > at position 7, following "'x'"`},
+				{typ: lexemeFilterGreaterThan, val: ">"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1582,7 +1827,9 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeFilterAt, val: "@"},
 				{typ: lexemeDotChild, val: ".child"},
 				{typ: lexemeFilterGreaterThanOrEqual, val: ">="},
-				{typ: lexemeError, val: `strings cannot be compared using >= at position 13, following ">="`},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1592,7 +1839,11 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeFilterBegin, val: "[?("},
 				{typ: lexemeFilterStringLiteral, val: "'x'"},
-				{typ: lexemeError, val: `strings cannot be compared using >= at position 7, following "'x'"`},
+				{typ: lexemeFilterGreaterThanOrEqual, val: ">="},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1653,7 +1904,9 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeFilterAt, val: "@"},
 				{typ: lexemeDotChild, val: ".child"},
 				{typ: lexemeFilterLessThan, val: "<"},
-				{typ: lexemeError, val: `strings cannot be compared using < at position 12, following "<"`},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1663,7 +1916,39 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeFilterBegin, val: "[?("},
 				{typ: lexemeFilterStringLiteral, val: "'x'"},
-				{typ: lexemeError, val: `strings cannot be compared using < at position 7, following "'x'"`},
+				{typ: lexemeFilterLessThan, val: "<"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter less than, timestamp on the right",
+			path: "$[?(@.child<'2024-01-01T00:00:00Z')]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterLessThan, val: "<"},
+				{typ: lexemeFilterStringLiteral, val: "'2024-01-01T00:00:00Z'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter less than, duration on the left",
+			path: "$[?('5m' < @.child)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterStringLiteral, val: "'5m'"},
+				{typ: lexemeFilterLessThan, val: "<"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1724,7 +2009,9 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeFilterAt, val: "@"},
 				{typ: lexemeDotChild, val: ".child"},
 				{typ: lexemeFilterLessThanOrEqual, val: "<="},
-				{typ: lexemeError, val: `strings cannot be compared using <= at position 13, following "<="`},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1734,7 +2021,11 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeRoot, val: "$"},
 				{typ: lexemeFilterBegin, val: "[?("},
 				{typ: lexemeFilterStringLiteral, val: "'x'"},
-				{typ: lexemeError, val: `strings cannot be compared using <= at position 7, following "'x'"`},
+				{typ: lexemeFilterLessThanOrEqual, val: "<="},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
 			},
 		},
 		{
@@ -1933,6 +2224,49 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeIdentity, val: ""},
 			},
 		},
+		{
+			name: "filter arithmetic",
+			path: "$[?(@.a * -(1 + @) % 2 > -@.b / 2 - 1.5)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeFilterMultiply, val: "*"},
+				{typ: lexemeFilterUnaryMinus, val: "-"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterIntegerLiteral, val: "1"},
+				{typ: lexemeFilterAdd, val: "+"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterModulo, val: "%"},
+				{typ: lexemeFilterIntegerLiteral, val: "2"},
+				{typ: lexemeFilterGreaterThan, val: ">"},
+				{typ: lexemeFilterUnaryMinus, val: "-"},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".b"},
+				{typ: lexemeFilterDivide, val: "/"},
+				{typ: lexemeFilterIntegerLiteral, val: "2"},
+				{typ: lexemeFilterSubtract, val: "-"},
+				{typ: lexemeFilterFloatLiteral, val: "1.5"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter arithmetic operator in child name",
+			path: "$[?(@.a-b*2 == 1)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".a-b*2"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterIntegerLiteral, val: "1"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
 		{
 			name: "filter regular expression",
 			path: "$[?(@.child=~/.*/)]",
@@ -1947,6 +2281,69 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeIdentity, val: ""},
 			},
 		},
+		{
+			name: "filter regular expression ignoring case",
+			path: "$[?(@.child=~/^a.*/i)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".child"},
+				{typ: lexemeFilterMatchesRegularExpression, val: "=~"},
+				{typ: lexemeFilterRegularExpressionLiteral, val: "/^a.*/i"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter key",
+			path: "$.labels[?(@~=~/^app/)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".labels"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterKey, val: "@~"},
+				{typ: lexemeFilterMatchesRegularExpression, val: "=~"},
+				{typ: lexemeFilterRegularExpressionLiteral, val: "/^app/"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter property names",
+			path: "$.labels[?(@=='true'||'x'==@~)]~",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".labels"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterStringLiteral, val: "'true'"},
+				{typ: lexemeFilterOr, val: "||"},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterKey, val: "@~"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeFilterPropertyName, val: "~"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter property names followed by child",
+			path: "$.labels[?(@~=='a')]~.b",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeDotChild, val: ".labels"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterKey, val: "@~"},
+				{typ: lexemeFilterEquality, val: "=="},
+				{typ: lexemeFilterStringLiteral, val: "'a'"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeFilterPropertyName, val: "~"},
+				{typ: lexemeDotChild, val: ".b"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
 		{
 			name: "filter regular expression with escaped /",
 			path: `$[?(@.child=~/\/.*/)]`,
@@ -2129,6 +2526,73 @@ func TestLexer(t *testing.T) {
 				{typ: lexemeIdentity, val: ""},
 			},
 		},
+		{
+			name: "filter with function",
+			path: "$[?(length(@.a) > 2)]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterFunctionName, val: "length"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterGreaterThan, val: ">"},
+				{typ: lexemeFilterIntegerLiteral, val: "2"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter with function with several arguments",
+			path: "$[?(match(@.a,'x') && 1 < count( @..b ))]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterFunctionName, val: "match"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeDotChild, val: ".a"},
+				{typ: lexemeFilterArgumentSeparator, val: ","},
+				{typ: lexemeFilterStringLiteral, val: "'x'"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterAnd, val: "&&"},
+				{typ: lexemeFilterIntegerLiteral, val: "1"},
+				{typ: lexemeFilterLessThan, val: "<"},
+				{typ: lexemeFilterFunctionName, val: "count"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterAt, val: "@"},
+				{typ: lexemeRecursiveDescent, val: "..b"},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter with function without arguments",
+			path: "$[?(f())]",
+			options: []Option{WithFunction("f", FunctionSignature{Result: LogicalType}, func([]FunctionValue) FunctionValue {
+				return FunctionValue{}
+			})},
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeFilterFunctionName, val: "f"},
+				{typ: lexemeFilterOpenBracket, val: "("},
+				{typ: lexemeFilterCloseBracket, val: ")"},
+				{typ: lexemeFilterEnd, val: ")]"},
+				{typ: lexemeIdentity, val: ""},
+			},
+		},
+		{
+			name: "filter with unknown function",
+			path: "$[?(f())]",
+			expected: []lexeme{
+				{typ: lexemeRoot, val: "$"},
+				{typ: lexemeFilterBegin, val: "[?("},
+				{typ: lexemeError, val: `unknown function f() at position 5, following "[?(f"`},
+			},
+		},
 	}
 
 	focussed := false
@@ -2145,6 +2609,9 @@ func TestLexer(t *testing.T) {
 		}
 		t.Run(tc.name, func(t *testing.T) {
 			l := lex("test", tc.path)
+			for _, opt := range tc.options {
+				opt(l.opts)
+			}
 			actual := []lexeme{}
 			for {
 				lexeme := l.nextLexeme()
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp/syntax"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits bounds the resources used by FindContext to apply a Path. A zero field leaves the corresponding resource
+// unbounded.
+type Limits struct {
+	// MaxVisitedNodes is the maximum number of nodes visited, including nodes visited while evaluating filters. A node
+	// is counted when it is reached, so the children of a node which are never reached are not counted.
+	MaxVisitedNodes int
+
+	// MaxResults is the maximum number of matching nodes.
+	MaxResults int
+
+	// MaxDepth is the maximum depth of any node visited, relative to the node to which the Path is applied. While a
+	// filter is evaluated, the depth of nodes reached from @ continues to increase, whereas nodes reached from $ are
+	// counted from the root node.
+	MaxDepth int
+
+	// MaxRegexpSize is the maximum size, in instructions of the compiled program, of any regular expression used
+	// by a filter.
+	MaxRegexpSize int
+}
+
+// ErrLimitExceeded is returned, wrapped in an error which says which limit was exceeded, by FindContext when applying
+// a Path exceeds the given limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// FindContext behaves like Find except that it stops and returns an error if the context is done or if applying the
+// Path exceeds the given limits, in which case the error wraps ErrLimitExceeded.
+func (p *Path) FindContext(ctx context.Context, node *yaml.Node, limits Limits) ([]*yaml.Node, error) {
+	locs, err := p.findContext(ctx, node, limits)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*yaml.Node{}
+	for _, l := range locs {
+		nodes = append(nodes, l.node)
+	}
+	return nodes, nil
+}
+
+// FindLocationsContext behaves like FindLocations except that it stops and returns an error in the same way as
+// FindContext.
+func (p *Path) FindLocationsContext(ctx context.Context, node *yaml.Node, limits Limits) ([]Location, error) {
+	locs, err := p.findContext(ctx, node, limits)
+	if err != nil {
+		return nil, err
+	}
+	locations := []Location{}
+	for _, l := range locs {
+		locations = append(locations, l.export())
+	}
+	return locations, nil
+}
+
+func (p *Path) findContext(ctx context.Context, node *yaml.Node, limits Limits) (locs []*location, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ee, ok := r.(evaluationError)
+			if !ok {
+				panic(r)
+			}
+			locs, err = nil, ee.err
+		}
+	}()
+
+	e := &evaluation{
+		ctx:    ctx,
+		limits: limits,
+	}
+	next := p.findFrom(&location{node: node, eval: e})
+	locs = []*location{}
+	for l, ok := next(); ok; l, ok = next() {
+		if limits.MaxResults > 0 && len(locs) == limits.MaxResults {
+			return nil, fmt.Errorf("%w: more than %d results", ErrLimitExceeded, limits.MaxResults)
+		}
+		locs = append(locs, l)
+	}
+	return locs, nil
+}
+
+// contextCheckInterval is the number of nodes visited between checks of whether the context is done.
+const contextCheckInterval = 1024
+
+// evaluation records the resources used while FindContext applies a Path.
+type evaluation struct {
+	ctx     context.Context
+	limits  Limits
+	visited int
+}
+
+// evaluationError is panicked to abandon applying a Path and is recovered by FindContext.
+type evaluationError struct {
+	err error
+}
+
+// visit accounts for a visit to the given location.
+func (e *evaluation) visit(l *location) {
+	e.visited++
+	if e.limits.MaxVisitedNodes > 0 && e.visited > e.limits.MaxVisitedNodes {
+		e.abort(fmt.Errorf("%w: more than %d nodes visited", ErrLimitExceeded, e.limits.MaxVisitedNodes))
+	}
+	if e.limits.MaxDepth > 0 && l.depth > e.limits.MaxDepth {
+		e.abort(fmt.Errorf("%w: node visited at depth greater than %d", ErrLimitExceeded, e.limits.MaxDepth))
+	}
+	if e.visited%contextCheckInterval == 0 {
+		if err := e.ctx.Err(); err != nil {
+			e.abort(err)
+		}
+	}
+}
+
+// checkRegexpSize checks the size of the program of a regular expression about to be used by a filter. It is a
+// no-op if e is nil.
+func (e *evaluation) checkRegexpSize(size int) {
+	if e != nil && e.limits.MaxRegexpSize > 0 && size > e.limits.MaxRegexpSize {
+		e.abort(fmt.Errorf("%w: regular expression program size %d is greater than %d", ErrLimitExceeded, size, e.limits.MaxRegexpSize))
+	}
+}
+
+func (e *evaluation) abort(err error) {
+	panic(evaluationError{err: err})
+}
+
+// regexpProgramSize returns the number of instructions in the compiled program of a Go regular expression, or zero
+// if the regular expression is invalid.
+func regexpProgramSize(expr string) int {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0
+	}
+	return len(prog.Inst)
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindContext(t *testing.T) {
+	input := `a:
+  b:
+    c:
+      d: x
+  e: [1, 2, 3, 4]
+f: abc
+`
+
+	cases := []struct {
+		name          string
+		path          string
+		options       []yamlpath.Option
+		limits        yamlpath.Limits
+		expectedCount int
+		expectedErr   string
+		focus         bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:          "no limits",
+			path:          "$..*",
+			expectedCount: 10,
+		},
+		{
+			name:          "limits not exceeded",
+			path:          "$..*",
+			limits:        yamlpath.Limits{MaxVisitedNodes: 100, MaxResults: 10, MaxDepth: 4, MaxRegexpSize: 100},
+			expectedCount: 10,
+		},
+		{
+			name:        "too many visited nodes",
+			path:        "$..*",
+			limits:      yamlpath.Limits{MaxVisitedNodes: 10},
+			expectedErr: "limit exceeded: more than 10 nodes visited",
+		},
+		{
+			name:        "too many visited nodes in filter",
+			path:        "$[?(@..d)]",
+			limits:      yamlpath.Limits{MaxVisitedNodes: 5},
+			expectedErr: "limit exceeded: more than 5 nodes visited",
+		},
+		{
+			name:        "too many results",
+			path:        "$.a.e[*]",
+			limits:      yamlpath.Limits{MaxResults: 3},
+			expectedErr: "limit exceeded: more than 3 results",
+		},
+		{
+			name:          "results at limit",
+			path:          "$.a.e[*]",
+			limits:        yamlpath.Limits{MaxResults: 4},
+			expectedCount: 4,
+		},
+		{
+			name:        "too deep",
+			path:        "$..d",
+			limits:      yamlpath.Limits{MaxDepth: 3},
+			expectedErr: "limit exceeded: node visited at depth greater than 3",
+		},
+		{
+			name:          "deep enough",
+			path:          "$.a.b.c.d",
+			limits:        yamlpath.Limits{MaxDepth: 4},
+			expectedCount: 1,
+		},
+		{
+			name:        "too deep in filter",
+			path:        "$.a.b[?(@.c.d)]",
+			limits:      yamlpath.Limits{MaxDepth: 3},
+			expectedErr: "limit exceeded: node visited at depth greater than 3",
+		},
+		{
+			name:          "filter from root counts depth from root",
+			path:          "$.a.b.c[?($.f)]",
+			limits:        yamlpath.Limits{MaxDepth: 3},
+			expectedCount: 1,
+		},
+		{
+			name:        "regular expression too large",
+			path:        "$[?(@.f =~ /a+b+c+d+e+/)]",
+			limits:      yamlpath.Limits{MaxRegexpSize: 5},
+			expectedErr: "limit exceeded: regular expression program size 12 is greater than 5",
+		},
+		{
+			name:        "regular expression in function too large",
+			path:        "$[?match(@, '[a-c]+x*y*z*')]",
+			options:     []yamlpath.Option{yamlpath.RFC9535},
+			limits:      yamlpath.Limits{MaxRegexpSize: 5},
+			expectedErr: "limit exceeded: regular expression program size 12 is greater than 5",
+		},
+		{
+			name:        "regular expression from node in function too large",
+			path:        "$[?match($.f, @)]",
+			options:     []yamlpath.Option{yamlpath.RFC9535},
+			limits:      yamlpath.Limits{MaxRegexpSize: 5},
+			expectedErr: "limit exceeded: regular expression program size 7 is greater than 5",
+		},
+		{
+			name:          "regular expression in function within limit",
+			path:          "$[?match(@, '[a-c]+')]",
+			options:       []yamlpath.Option{yamlpath.RFC9535},
+			limits:        yamlpath.Limits{MaxRegexpSize: 20},
+			expectedCount: 1,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			actual, err := p.FindContext(context.Background(), &n, tc.limits)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+				require.Len(t, actual, tc.expectedCount)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+				require.True(t, errors.Is(err, yamlpath.ErrLimitExceeded))
+				require.Nil(t, actual)
+			}
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestFindContextWideSequence(t *testing.T) {
+	var n yaml.Node
+	err := yaml.Unmarshal([]byte("w: [{x: 1}"+strings.Repeat(", 0", 10000)+"]"), &n)
+	require.NoError(t, err)
+
+	// only the nodes reached before the filter finds x are visited, not the other items of the sequence
+	p, err := yamlpath.NewPath("$[?(@..x)]")
+	require.NoError(t, err)
+	actual, err := p.FindContext(context.Background(), &n, yamlpath.Limits{MaxVisitedNodes: 10})
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+
+	p, err = yamlpath.NewPath("$..x")
+	require.NoError(t, err)
+	actual, err = p.FindContext(context.Background(), &n, yamlpath.Limits{MaxVisitedNodes: 10})
+	require.EqualError(t, err, "limit exceeded: more than 10 nodes visited")
+	require.Nil(t, actual)
+}
+
+func TestFindContextCancelled(t *testing.T) {
+	var n yaml.Node
+	err := yaml.Unmarshal([]byte(`[[[[1, 2, 3]]]]`), &n)
+	require.NoError(t, err)
+
+	p, err := yamlpath.NewPath("$..*..*..*..*")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	actual, err := p.FindContext(ctx, &n, yamlpath.Limits{})
+	require.Equal(t, context.Canceled, err)
+	require.Nil(t, actual)
+}
+
+func TestFindContextCancelledDuringEvaluation(t *testing.T) {
+	items := make([]int, 5000)
+	var n yaml.Node
+	err := n.Encode(items)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	cancelling := yamlpath.WithFunction("cancelling", yamlpath.FunctionSignature{
+		Params: []yamlpath.FunctionType{yamlpath.ValueType},
+		Result: yamlpath.LogicalType,
+	}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+		calls++
+		cancel()
+		return yamlpath.FunctionValue{Logical: true}
+	})
+
+	p, err := yamlpath.NewPathWithOptions("$[?(cancelling(@))]", cancelling)
+	require.NoError(t, err)
+
+	actual, err := p.FindContext(ctx, &n, yamlpath.Limits{})
+	require.Equal(t, context.Canceled, err)
+	require.Nil(t, actual)
+	require.Less(t, calls, len(items))
+}
+
+func TestFindLocationsContext(t *testing.T) {
+	var n yaml.Node
+	err := yaml.Unmarshal([]byte("a: [1, 2, 3] # numbers\n"), &n)
+	require.NoError(t, err)
+
+	p, err := yamlpath.NewPath("$.a")
+	require.NoError(t, err)
+
+	actual, err := p.FindLocationsContext(context.Background(), &n, yamlpath.Limits{MaxResults: 1})
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "$['a']", actual[0].Path)
+	require.Equal(t, "# numbers", actual[0].Comment)
+
+	p, err = yamlpath.NewPath("$.a[*]")
+	require.NoError(t, err)
+
+	actual, err = p.FindLocationsContext(context.Background(), &n, yamlpath.Limits{MaxResults: 2})
+	require.EqualError(t, err, "limit exceeded: more than 2 results")
+	require.Nil(t, actual)
+}
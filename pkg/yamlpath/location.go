@@ -0,0 +1,522 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Location describes where a node matching a Path was found.
+type Location struct {
+	// Node is the matching node.
+	Node *yaml.Node
+
+	// Parent is the mapping, sequence, or document node whose content includes Node, or nil if Node is the node to
+	// which the Path was applied. If Node was reached by following an alias (see FollowAliases), Parent is the node
+	// whose content includes the alias node, so the content of Parent at Key or Index is the alias node, not Node.
+	Parent *yaml.Node
+
+	// Key is the key of Node if Parent is a mapping node, otherwise nil. If Node is a property name (matched using
+	// the ~ operator), Key is Node.
+	Key *yaml.Node
+
+	// Index is the position of Node in Parent if Parent is a sequence or document node, otherwise -1.
+	Index int
+
+	// Path is the normalized path of Node relative to the node to which the Path was applied, for example
+	// $['spec']['containers'][0]['image']. The normalized path of a property name ends in ~.
+	Path string
+
+	// Comment is the text of the comments of Node and, if Node is a mapping value, of its key, in the form
+	// produced by the comment() filter function.
+	Comment string
+}
+
+// FindLocations applies the Path to a YAML node and returns the locations of the subnodes which match the Path.
+func (p *Path) FindLocations(node *yaml.Node) ([]Location, error) {
+	locations := []Location{}
+	for _, l := range p.find(node).toArray() {
+		locations = append(locations, l.export())
+	}
+	return locations, nil // currently, errors are not possible
+}
+
+// location records how a node was reached while applying a Path.
+type location struct {
+	node   *yaml.Node
+	parent *location   // nil for the node to which the Path was applied
+	index  int         // index of node in the content of the parent node
+	depth  int         // number of ancestors of node, excluding any document node, visited while applying the Path
+	eval   *evaluation // nil unless the Path is being applied by FindContext
+
+	followAliases bool // true if alias nodes are replaced by their anchored nodes, see FollowAliases
+	mergeKeys     bool // true if merge keys are resolved, see MergeKeys
+}
+
+// child returns the location of the child at the given index in the content of the location's node.
+func (l *location) child(i int) *location {
+	c := &location{
+		node:   l.node.Content[i],
+		parent: l,
+		index:  i,
+		depth:  l.depth,
+		eval:   l.eval,
+
+		followAliases: l.followAliases,
+		mergeKeys:     l.mergeKeys,
+	}
+	if c.followAliases {
+		c.node = c.dealias()
+	}
+	if l.node.Kind != yaml.DocumentNode {
+		c.depth++
+	}
+	if c.eval != nil {
+		c.eval.visit(c)
+	}
+	return c
+}
+
+// restart returns a location, with no parent, for a node from which a path is applied in order to evaluate a filter
+// at the location.
+func (l *location) restart(node *yaml.Node) *location {
+	return &location{
+		node: node,
+		eval: l.eval,
+
+		followAliases: l.followAliases,
+		mergeKeys:     l.mergeKeys,
+	}
+}
+
+// up returns the location of the node whose content includes the location's node, or nil if the location's node is
+// the node to which the Path was applied or the content of a document node.
+func (l *location) up() *location {
+	if l.parent == nil || l.parent.node.Kind == yaml.DocumentNode {
+		return nil
+	}
+	return l.parent
+}
+
+// dealias returns the node to which the location's node refers if the location's node is an alias node, unless the
+// node referred to is the node at an ancestor location, in which case the location's node is returned to avoid a
+// cycle. Otherwise, dealias returns the location's node.
+func (l *location) dealias() *yaml.Node {
+	n := l.node
+	if n.Kind != yaml.AliasNode || n.Alias == nil {
+		return n
+	}
+	for a := l.parent; a != nil; a = a.parent {
+		if a.node == n.Alias {
+			return n
+		}
+	}
+	return n.Alias
+}
+
+// key returns the key of the location's node if the node is a mapping value, otherwise nil.
+func (l *location) key() *yaml.Node {
+	if l.parent == nil || l.parent.node.Kind != yaml.MappingNode || l.index%2 == 0 {
+		return nil
+	}
+	return l.parent.node.Content[l.index-1]
+}
+
+// keyLocation returns the location of the key of the location's node if the node is a mapping value, otherwise nil.
+func (l *location) keyLocation() *location {
+	if l.key() == nil {
+		return nil
+	}
+	return l.parent.child(l.index - 1)
+}
+
+// isKey returns true if and only if the location's node is the key of a mapping node.
+func (l *location) isKey() bool {
+	return l.parent != nil && l.parent.node.Kind == yaml.MappingNode && l.index%2 == 0
+}
+
+func (l *location) export() Location {
+	e := Location{
+		Node:    l.node,
+		Index:   -1,
+		Path:    l.normalizedPath(),
+		Comment: comments(l.node, l.key()),
+	}
+	if l.parent == nil {
+		return e
+	}
+	e.Parent = l.parent.node
+	switch e.Parent.Kind {
+	case yaml.MappingNode:
+		e.Key = e.Parent.Content[l.index-l.index%2]
+	case yaml.SequenceNode, yaml.DocumentNode:
+		e.Index = l.index
+	}
+	return e
+}
+
+// normalizedPath returns the normalized path of the location's node as defined by RFC 9535, except that property
+// names are denoted by a trailing ~.
+func (l *location) normalizedPath() string {
+	if l.parent == nil {
+		return root
+	}
+	p := l.parent.normalizedPath()
+	switch l.parent.node.Kind {
+	case yaml.MappingNode:
+		p += fmt.Sprintf("[%s]", normalizedName(l.parent.node.Content[l.index-l.index%2].Value))
+		if l.isKey() {
+			p += propertyName
+		}
+	case yaml.SequenceNode:
+		p += fmt.Sprintf("[%d]", l.index)
+	}
+	return p
+}
+
+// normalizedName quotes a child name as required by RFC 9535 normalized paths.
+func normalizedName(name string) string {
+	var b strings.Builder
+	b.WriteString("'")
+	for _, r := range name {
+		switch r {
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u00`)
+				if r < 0x10 {
+					b.WriteString("0")
+				}
+				b.WriteString(strconv.FormatInt(int64(r), 16))
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString("'")
+	return b.String()
+}
+
+// locationIterator iterates over locations in the manner of yit.Iterator.
+type locationIterator func() (*location, bool)
+
+func fromLocations(locs ...*location) locationIterator {
+	i := 0
+	return func() (*location, bool) {
+		if i >= len(locs) {
+			return nil, false
+		}
+		l := locs[i]
+		i++
+		return l, true
+	}
+}
+
+func fromLocationIterators(its ...locationIterator) locationIterator {
+	return func() (*location, bool) {
+		for len(its) > 0 {
+			if l, ok := its[0](); ok {
+				return l, true
+			}
+			its = its[1:]
+		}
+		return nil, false
+	}
+}
+
+// parents iterates over the locations of the parents of the locations' nodes. A node is produced once for each of
+// its children.
+func (next locationIterator) parents() locationIterator {
+	return func() (*location, bool) {
+		for l, ok := next(); ok; l, ok = next() {
+			if p := l.up(); p != nil {
+				return p, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// ancestors iterates over the locations of the ancestors of the locations' nodes, nearest first.
+func (next locationIterator) ancestors() locationIterator {
+	var l *location
+	return func() (*location, bool) {
+		for {
+			if l != nil {
+				if l = l.up(); l != nil {
+					return l, true
+				}
+			}
+			var ok bool
+			if l, ok = next(); !ok {
+				return nil, false
+			}
+		}
+	}
+}
+
+// valueKeys iterates over the locations of the keys of the locations' nodes, omitting any location whose node is not
+// a mapping value.
+func (next locationIterator) valueKeys() locationIterator {
+	return func() (*location, bool) {
+		for l, ok := next(); ok; l, ok = next() {
+			if k := l.keyLocation(); k != nil {
+				return k, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// recurse iterates over the locations and all their descendants (including the keys of mapping nodes) in
+// document order.
+func (next locationIterator) recurse() locationIterator {
+	return next.depthFirst(func(l *location) cursor {
+		return cursor{loc: l, step: 1}
+	})
+}
+
+// values iterates over the mapping values and sequence items of the locations' nodes.
+func (next locationIterator) values() locationIterator {
+	var parent *location
+	var entries []mapEntry // the entries of parent if merge keys are resolved
+	i := 0
+	return func() (*location, bool) {
+		for {
+			if parent != nil {
+				switch parent.node.Kind {
+				case yaml.MappingNode:
+					if parent.mergeKeys {
+						if i < len(entries) {
+							i++
+							return entries[i-1].value(), true
+						}
+						break
+					}
+					if i+1 < len(parent.node.Content) {
+						i += 2
+						return parent.child(i - 1), true
+					}
+				case yaml.SequenceNode:
+					if i < len(parent.node.Content) {
+						i++
+						return parent.child(i - 1), true
+					}
+				}
+			}
+			var ok bool
+			if parent, ok = next(); !ok {
+				return nil, false
+			}
+			if parent.mergeKeys {
+				entries = parent.entries()
+			}
+			i = 0
+		}
+	}
+}
+
+// keys iterates over the keys of the locations' mapping nodes.
+func (next locationIterator) keys() locationIterator {
+	var parent *location
+	var entries []mapEntry // the entries of parent if merge keys are resolved
+	i := 0
+	return func() (*location, bool) {
+		for {
+			if parent != nil && parent.mergeKeys {
+				if i < len(entries) {
+					i++
+					return entries[i-1].key(), true
+				}
+			} else if parent != nil && parent.node.Kind == yaml.MappingNode && i < len(parent.node.Content) {
+				i += 2
+				return parent.child(i - 2), true
+			}
+			var ok bool
+			if parent, ok = next(); !ok {
+				return nil, false
+			}
+			if parent.mergeKeys {
+				entries = parent.entries()
+			}
+			i = 0
+		}
+	}
+}
+
+// indices iterates over the items of the locations' sequence nodes at the indices produced, for the length of each
+// sequence, by the given function, ignoring any indices which are out of range.
+func (next locationIterator) indices(indices func(length int) indexIterator) locationIterator {
+	var parent *location
+	var it indexIterator
+	return func() (*location, bool) {
+		for {
+			if parent != nil && parent.node.Kind == yaml.SequenceNode {
+				for s, ok := it(); ok; s, ok = it() {
+					if s >= 0 && s < len(parent.node.Content) {
+						return parent.child(s), true
+					}
+				}
+			}
+			var ok bool
+			if parent, ok = next(); !ok {
+				return nil, false
+			}
+			it = indices(len(parent.node.Content))
+		}
+	}
+}
+
+// filter iterates over the locations whose nodes satisfy the given filter.
+func (next locationIterator) filter(f filter, root *yaml.Node) locationIterator {
+	return func() (*location, bool) {
+		for l, ok := next(); ok; l, ok = next() {
+			if f(l, root) {
+				return l, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// descendants iterates over the locations and all their descendants, excluding the keys of mapping nodes, in
+// document order.
+func (next locationIterator) descendants() locationIterator {
+	return next.depthFirst(func(l *location) cursor {
+		switch l.node.Kind {
+		case yaml.MappingNode:
+			return cursor{loc: l, i: 1, step: 2}
+		case yaml.SequenceNode, yaml.DocumentNode:
+			return cursor{loc: l, step: 1}
+		default:
+			return cursor{loc: l, i: len(l.node.Content)}
+		}
+	})
+}
+
+// cursor is the position of a depth-first traversal in the content of the node at a location.
+type cursor struct {
+	loc  *location
+	i    int // the index in the content of the next child to traverse
+	step int // the difference between the indices of successive children to traverse
+}
+
+// depthFirst iterates over the locations and their descendants in document order, traversing the children of each
+// node as described by the cursor returned by children. Each child's location is created only when it is produced,
+// so that producing the first few descendants of a node with many children is cheap.
+func (next locationIterator) depthFirst(children func(*location) cursor) locationIterator {
+	var stack []cursor
+	return func() (*location, bool) {
+		for len(stack) > 0 {
+			top := len(stack) - 1
+			c := stack[top]
+			if c.i >= len(c.loc.node.Content) {
+				stack = stack[:top]
+				continue
+			}
+			stack[top].i += c.step
+			l := c.loc.child(c.i)
+			stack = append(stack, children(l))
+			return l, true
+		}
+		l, ok := next()
+		if !ok {
+			return nil, false
+		}
+		stack = append(stack, children(l))
+		return l, true
+	}
+}
+
+// inDocumentOrder iterates over the locations in document order, omitting any location whose node has already
+// occurred at an earlier location. All the locations are consumed before the first is produced.
+func (next locationIterator) inDocumentOrder() locationIterator {
+	var locs []*location
+	return func() (*location, bool) {
+		if locs == nil {
+			all := next.toArray()
+			keys := make(map[*location][]int, len(all))
+			for _, l := range all {
+				keys[l] = l.indexPath()
+			}
+			sort.SliceStable(all, func(i, j int) bool {
+				return lessIndexPath(keys[all[i]], keys[all[j]])
+			})
+			seen := map[*yaml.Node]bool{}
+			locs = []*location{}
+			for _, l := range all {
+				if !seen[l.node] {
+					seen[l.node] = true
+					locs = append(locs, l)
+				}
+			}
+		}
+		if len(locs) == 0 {
+			return nil, false
+		}
+		l := locs[0]
+		locs = locs[1:]
+		return l, true
+	}
+}
+
+// indexPath returns the indices by which the location's node was reached from the node with no parent.
+func (l *location) indexPath() []int {
+	var path []int
+	for ; l.parent != nil; l = l.parent {
+		path = append(path, l.index)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// lessIndexPath returns true if and only if the node with index path p precedes the node with index path q in
+// document order. An ancestor precedes its descendants.
+func lessIndexPath(p, q []int) bool {
+	for i := 0; i < len(p) && i < len(q); i++ {
+		if p[i] != q[i] {
+			return p[i] < q[i]
+		}
+	}
+	return len(p) < len(q)
+}
+
+func (next locationIterator) toArray() []*location {
+	locs := []*location{}
+	for l, ok := next(); ok; l, ok = next() {
+		locs = append(locs, l)
+	}
+	return locs
+}
+
+func (next locationIterator) nodes() []*yaml.Node {
+	nodes := []*yaml.Node{}
+	for l, ok := next(); ok; l, ok = next() {
+		nodes = append(nodes, l.node)
+	}
+	return nodes
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindLocations(t *testing.T) {
+	y := `spec:
+  containers:
+    - name: nginx
+      # renovate: datasource=docker
+      image: nginx # pinned
+    - name: sidecar
+      image: envoy
+"it's\n": 1
+`
+	var n yaml.Node
+	err := yaml.Unmarshal([]byte(y), &n)
+	require.NoError(t, err)
+
+	type location struct {
+		path    string
+		value   string
+		key     string // value of the key node, if any
+		index   int
+		parent  yaml.Kind
+		comment string
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		expected []location
+		focus    bool // if true, run only tests with focus set to true
+	}{
+		{
+			name: "identity",
+			path: "",
+			expected: []location{
+				{path: "$", index: -1},
+			},
+		},
+		{
+			name: "root",
+			path: "$",
+			expected: []location{
+				{path: "$", index: 0, parent: yaml.DocumentNode},
+			},
+		},
+		{
+			name: "children and array subscript",
+			path: "$.spec.containers[1].image",
+			expected: []location{
+				{path: "$['spec']['containers'][1]['image']", value: "envoy", key: "image", index: -1, parent: yaml.MappingNode},
+			},
+		},
+		{
+			name: "wildcard",
+			path: "$.spec.containers.*.name",
+			expected: []location{
+				{path: "$['spec']['containers'][0]['name']", value: "nginx", key: "name", index: -1, parent: yaml.MappingNode},
+				{path: "$['spec']['containers'][1]['name']", value: "sidecar", key: "name", index: -1, parent: yaml.MappingNode},
+			},
+		},
+		{
+			name: "recursive descent",
+			path: "$..image",
+			expected: []location{
+				{path: "$['spec']['containers'][0]['image']", value: "nginx", key: "image", index: -1, parent: yaml.MappingNode, comment: "# renovate: datasource=docker\n# pinned"},
+				{path: "$['spec']['containers'][1]['image']", value: "envoy", key: "image", index: -1, parent: yaml.MappingNode},
+			},
+		},
+		{
+			name: "filter",
+			path: "$.spec.containers[?(@.name == 'sidecar')]",
+			expected: []location{
+				{path: "$['spec']['containers'][1]", index: 1, parent: yaml.SequenceNode},
+			},
+		},
+		{
+			name: "property name",
+			path: "$.spec.containers[0][*]~",
+			expected: []location{
+				{path: "$['spec']['containers'][0]['name']~", value: "name", key: "name", index: -1, parent: yaml.MappingNode},
+				{path: "$['spec']['containers'][0]['image']~", value: "image", key: "image", index: -1, parent: yaml.MappingNode, comment: "# renovate: datasource=docker"},
+			},
+		},
+		{
+			name: "escaped child name",
+			path: "$[\"it's\n\"]",
+			expected: []location{
+				{path: `$['it\'s\n']`, value: "1", key: "it's\n", index: -1, parent: yaml.MappingNode},
+			},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.NewPath(tc.path)
+			require.NoError(t, err)
+
+			actual, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualLocations := []location{}
+			for _, a := range actual {
+				l := location{
+					path:    a.Path,
+					value:   a.Node.Value,
+					index:   a.Index,
+					comment: a.Comment,
+				}
+				if a.Key != nil {
+					l.key = a.Key.Value
+				}
+				if a.Parent != nil {
+					l.parent = a.Parent.Kind
+				}
+				actualLocations = append(actualLocations, l)
+			}
+			require.Equal(t, tc.expected, actualLocations)
+
+			nodes, err := p.Find(&n)
+			require.NoError(t, err)
+			for i, a := range actual {
+				require.Same(t, nodes[i], a.Node)
+			}
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestFindLocationsFollowingAliases(t *testing.T) {
+	y := `x: &a {b: 1}
+c: *a
+s: [*a]
+`
+	var n yaml.Node
+	err := yaml.Unmarshal([]byte(y), &n)
+	require.NoError(t, err)
+
+	type location struct {
+		path    string
+		kind    yaml.Kind // kind of the matching node
+		key     string    // value of the key node, if any
+		index   int
+		parent  yaml.Kind
+		content yaml.Kind // kind of the node in the content of the parent at the key or index
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		expected []location
+		focus    bool // if true, run only tests with focus set to true
+	}{
+		{
+			name: "mapping value reached through alias",
+			path: "$.c",
+			expected: []location{
+				{path: "$['c']", kind: yaml.MappingNode, key: "c", index: -1, parent: yaml.MappingNode, content: yaml.AliasNode},
+			},
+		},
+		{
+			name: "sequence item reached through alias",
+			path: "$.s[0]",
+			expected: []location{
+				{path: "$['s'][0]", kind: yaml.MappingNode, index: 0, parent: yaml.SequenceNode, content: yaml.AliasNode},
+			},
+		},
+		{
+			name: "child of node reached through alias",
+			path: "$.c.b",
+			expected: []location{
+				{path: "$['c']['b']", kind: yaml.ScalarNode, key: "b", index: -1, parent: yaml.MappingNode, content: yaml.ScalarNode},
+			},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.NewPathWithOptions(tc.path, yamlpath.FollowAliases)
+			require.NoError(t, err)
+
+			actual, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualLocations := []location{}
+			for _, a := range actual {
+				l := location{
+					path:   a.Path,
+					kind:   a.Node.Kind,
+					index:  a.Index,
+					parent: a.Parent.Kind,
+				}
+				if a.Key != nil {
+					l.key = a.Key.Value
+					for i := 0; i+1 < len(a.Parent.Content); i += 2 {
+						if a.Parent.Content[i] == a.Key {
+							l.content = a.Parent.Content[i+1].Kind
+						}
+					}
+				} else {
+					l.content = a.Parent.Content[a.Index].Kind
+				}
+				actualLocations = append(actualLocations, l)
+			}
+			require.Equal(t, tc.expected, actualLocations)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
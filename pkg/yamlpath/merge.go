@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import "gopkg.in/yaml.v3"
+
+const mergeTag = "!!merge"
+
+// mapEntry is an entry, that is, a key and its value, of a mapping node.
+type mapEntry struct {
+	mapping *location // the location of the mapping node whose content includes the entry
+	index   int       // the index of the entry's key in the content of the mapping node
+}
+
+// key returns the location of the entry's key.
+func (e mapEntry) key() *location {
+	return e.mapping.child(e.index)
+}
+
+// value returns the location of the entry's value.
+func (e mapEntry) value() *location {
+	return e.mapping.child(e.index + 1)
+}
+
+// name returns the name of the entry's key.
+func (e mapEntry) name() string {
+	return e.mapping.node.Content[e.index].Value
+}
+
+// entries returns the entries of the location's node, in order, if it is a mapping node. If merge keys are resolved
+// (see MergeKeys), each merge key is replaced by the entries of the mapping, or mappings, to which it refers, except
+// for entries whose keys are already present. Entries of the location's node take precedence over merged entries
+// and entries of a mapping earlier in a sequence of mappings to be merged take precedence over those of later
+// mappings.
+func (l *location) entries() []mapEntry {
+	if l.node.Kind != yaml.MappingNode {
+		return nil
+	}
+	entries := []mapEntry{}
+	if !l.mergeKeys {
+		for i := 0; i+1 < len(l.node.Content); i += 2 {
+			entries = append(entries, mapEntry{mapping: l, index: i})
+		}
+		return entries
+	}
+
+	present := map[string]bool{}
+	add := func(e mapEntry) {
+		if !present[e.name()] {
+			present[e.name()] = true
+			entries = append(entries, e)
+		}
+	}
+	merges := []int{}
+	for i := 0; i+1 < len(l.node.Content); i += 2 {
+		if isMergeKey(l.node.Content[i]) {
+			merges = append(merges, i)
+			continue
+		}
+		add(mapEntry{mapping: l, index: i})
+	}
+	for _, i := range merges {
+		for _, m := range l.child(i + 1).merged() {
+			for _, e := range m.entries() {
+				add(e)
+			}
+		}
+	}
+	return entries
+}
+
+// merged returns the locations of the mappings referred to by the value of a merge key at the location: either a
+// single mapping or a sequence of mappings, each of which is usually an alias.
+func (l *location) merged() []*location {
+	l.node = l.dealias()
+	switch l.node.Kind {
+	case yaml.MappingNode:
+		return []*location{l}
+	case yaml.SequenceNode:
+		mappings := []*location{}
+		for i := range l.node.Content {
+			c := l.child(i)
+			if c.node = c.dealias(); c.node.Kind == yaml.MappingNode {
+				mappings = append(mappings, c)
+			}
+		}
+		return mappings
+	}
+	return nil
+}
+
+// namedEntries returns the entries of the location's node, if it is a mapping node, whose keys have the given names,
+// in the order of the names.
+func (l *location) namedEntries(names []string) []mapEntry {
+	entries := l.entries()
+	named := []mapEntry{}
+	for _, name := range names {
+		for _, e := range entries {
+			if e.name() == name {
+				named = append(named, e)
+			}
+		}
+	}
+	return named
+}
+
+func isMergeKey(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == mergeTag
+}
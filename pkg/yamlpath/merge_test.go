@@ -0,0 +1,166 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeKeys(t *testing.T) {
+	input := `base: &base
+  image: base:1
+  restart: always
+logging: &logging
+  driver: json
+  restart: never
+services:
+  web:
+    <<: *base
+    image: web:2
+  worker:
+    <<: [*logging, *base]
+    command: run
+  literal:
+    "<<": *base
+  loop: &loop
+    <<: *loop
+    name: loop
+`
+
+	cases := []struct {
+		name          string
+		path          string
+		options       []yamlpath.Option
+		expectedPaths []string // the normalized paths of the matching nodes
+		focus         bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name:          "merge keys not resolved",
+			path:          "$.services.web.restart",
+			expectedPaths: []string{},
+		},
+		{
+			name:          "merged child",
+			path:          "$.services.web.restart",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['web']['<<']['restart']"},
+		},
+		{
+			name:          "overridden child",
+			path:          "$.services.web.image",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['web']['image']"},
+		},
+		{
+			name:          "earlier mapping takes precedence",
+			path:          "$.services.worker['restart','image']",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['worker']['<<'][0]['restart']", "$['services']['worker']['<<'][1]['image']"},
+		},
+		{
+			name:    "all children",
+			path:    "$.services.worker.*",
+			options: []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{
+				"$['services']['worker']['command']",
+				"$['services']['worker']['<<'][0]['driver']",
+				"$['services']['worker']['<<'][0]['restart']",
+				"$['services']['worker']['<<'][1]['image']",
+			},
+		},
+		{
+			name:    "property names",
+			path:    "$.services.web[*]~",
+			options: []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{
+				"$['services']['web']['image']~",
+				"$['services']['web']['<<']['restart']~",
+			},
+		},
+		{
+			name:          "filter",
+			path:          "$.services.*[?(@.restart == 'always')]",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['web']"},
+		},
+		{
+			name:          "key filter",
+			path:          "$.services.worker[?(@~ =~ /^(restart|command)$/)]",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['worker']['command']", "$['services']['worker']['<<'][0]['restart']"},
+		},
+		{
+			name:          "quoted key is not a merge key",
+			path:          "$.services.literal.image",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{},
+		},
+		{
+			name:          "cyclic merge",
+			path:          "$.services.loop.*",
+			options:       []yamlpath.Option{yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['loop']['name']"},
+		},
+		{
+			name:          "RFC 9535 name selector",
+			path:          "$.services.web.restart",
+			options:       []yamlpath.Option{yamlpath.RFC9535, yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['web']['<<']['restart']"},
+		},
+		{
+			name:          "RFC 9535 filter",
+			path:          "$.services[?@.driver]",
+			options:       []yamlpath.Option{yamlpath.RFC9535, yamlpath.MergeKeys},
+			expectedPaths: []string{"$['services']['worker']"},
+		},
+		{
+			name:          "with aliases followed",
+			path:          "$.services.web..restart",
+			options:       []yamlpath.Option{yamlpath.MergeKeys, yamlpath.FollowAliases, yamlpath.DocumentOrder},
+			expectedPaths: []string{"$['services']['web']['<<']['restart']"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			locations, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualPaths := []string{}
+			for _, l := range locations {
+				actualPaths = append(actualPaths, l.Path)
+			}
+			require.Equal(t, tc.expectedPaths, actualPaths)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
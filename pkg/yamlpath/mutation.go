@@ -0,0 +1,220 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set replaces each node in root which matches the Path with a copy of the given value.
+// If a property name (matched using the ~ operator) is replaced, the corresponding key is renamed.
+// Comments of a replaced node are carried over to its replacement unless the replacement has comments of its own.
+func (p *Path) Set(root, value *yaml.Node) error {
+	value = unwrapDocument(value)
+	for _, l := range p.find(root).toArray() {
+		if l.parent == nil {
+			// the root node has no parent, so modify it in place
+			replacement := copyNode(value)
+			if l.node.Kind == yaml.DocumentNode {
+				l.node.Content = []*yaml.Node{replacement}
+				continue
+			}
+			inheritComments(replacement, l.node)
+			*l.node = *replacement
+			continue
+		}
+		parent := l.parent.node
+		if i := l.contentIndex(p.opts.followAliases); i >= 0 {
+			parent.Content[i] = copyNode(value)
+			inheritComments(parent.Content[i], l.node)
+		}
+	}
+	return nil
+}
+
+// Delete removes each node in root which matches the Path. A matched mapping value or property name is removed
+// together with its key or value, respectively. A matched sequence item is removed from its sequence.
+// The root node of a document cannot be deleted.
+func (p *Path) Delete(root *yaml.Node) error {
+	locs := p.find(root).toArray()
+	for _, l := range locs {
+		if l.parent == nil || l.parent.node.Kind == yaml.DocumentNode {
+			return errors.New("cannot delete the root node")
+		}
+	}
+	for _, l := range locs {
+		parent := l.parent.node
+		i := l.contentIndex(p.opts.followAliases)
+		if i < 0 {
+			continue // already deleted
+		}
+		if parent.Kind == yaml.MappingNode {
+			i -= i % 2 // delete the key and value together
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			continue
+		}
+		parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+	}
+	return nil
+}
+
+// Upsert behaves like Set except that, if the Path ends with one or more child names, a child missing from a
+// matching mapping node is added with a copy of the given value. Any mapping nodes missing from the rest of the
+// path are also added, provided they are specified by child names.
+func (p *Path) Upsert(root, value *yaml.Node) error {
+	return p.upsert(root, unwrapDocument(value), true)
+}
+
+// upsert adds a copy of the given value to each mapping node matching the path without its last child name.
+// Existing children are replaced only if overwrite is true.
+func (p *Path) upsert(root, value *yaml.Node, overwrite bool) error {
+	parent, childNames, err := p.splitLastChild()
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		if overwrite {
+			return p.Set(root, value)
+		}
+		return nil
+	}
+
+	// create any missing mapping nodes leading up to the children
+	if err := parent.upsert(root, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, false); err != nil {
+		return err
+	}
+
+	nodes, err := parent.Find(root)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		n = unwrapDocument(n)
+		if n.Kind != yaml.MappingNode {
+			continue
+		}
+		for _, childName := range childNames {
+			if i := keyIndex(n, childName); i >= 0 {
+				if overwrite {
+					old := n.Content[i+1]
+					n.Content[i+1] = copyNode(value)
+					inheritComments(n.Content[i+1], old)
+				}
+				continue
+			}
+			n.Content = append(n.Content, &yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Tag:   strTag,
+				Value: childName,
+			}, copyNode(value))
+		}
+	}
+	return nil
+}
+
+// splitLastChild returns a path which matches the parents of the nodes matched by p together with the child names
+// which p then matches. If p does not end with child names, or the child names follow a recursive descent, or the
+// parents cannot be matched by a path of their own, a nil path is returned.
+func (p *Path) splitLastChild() (*Path, []string, error) {
+	if p.opts.rfc9535 {
+		return rfc9535SplitLastChild(p.expr, &p.opts)
+	}
+	if p.ast == nil || len(p.ast.Segments) == 0 {
+		return nil, nil, nil
+	}
+
+	segments := p.ast.Segments
+	last, ok := segments[len(segments)-1].(ChildSegment)
+	if !ok {
+		return nil, nil, nil
+	}
+	if _, recursive := previousSegment(segments, len(segments)-1).(RecursiveDescentSegment); recursive {
+		return nil, nil, nil
+	}
+
+	parentAST := &AST{Segments: segments[:len(segments)-1]}
+	parent, err := compileAST(parentAST, &p.opts)
+	if err != nil {
+		return nil, nil, nil
+	}
+	parent.ast = parentAST
+	parent.opts = p.opts
+	return parent, last.Names, nil
+}
+
+// contentIndex returns the index of the location's node in the content of its parent node, preferring the index at
+// which the node was found, or -1 if the node is no longer present. If aliases is true, an alias node referring to
+// the location's node stands for the node.
+func (l *location) contentIndex(aliases bool) int {
+	parent := l.parent.node
+	if l.index < len(parent.Content) {
+		if c := parent.Content[l.index]; c == l.node || aliases && c.Kind == yaml.AliasNode && c.Alias == l.node {
+			return l.index
+		}
+	}
+	return indexOf(parent, l.node, aliases)
+}
+
+// indexOf returns the index of the given child in the content of the given parent node or -1 if it is not present.
+// If the child is not present and aliases is true, the index of an alias node referring to the child is returned
+// instead, if there is one.
+func indexOf(parent, child *yaml.Node, aliases bool) int {
+	for i, c := range parent.Content {
+		if c == child {
+			return i
+		}
+	}
+	if aliases {
+		for i, c := range parent.Content {
+			if c.Kind == yaml.AliasNode && c.Alias == child {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// keyIndex returns the index of the key with the given name in the content of the given mapping node or -1 if
+// there is no such key.
+func keyIndex(mapping *yaml.Node, childName string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == childName {
+			return i
+		}
+	}
+	return -1
+}
+
+func unwrapDocument(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// copyNode returns a deep copy of the given node. Aliases are not copied and continue to refer to their anchors.
+func copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	if node.Content != nil {
+		c.Content = make([]*yaml.Node, len(node.Content))
+		for i, n := range node.Content {
+			c.Content[i] = copyNode(n)
+		}
+	}
+	return &c
+}
+
+func inheritComments(replacement, original *yaml.Node) {
+	if replacement.HeadComment != "" || replacement.LineComment != "" || replacement.FootComment != "" {
+		return
+	}
+	replacement.HeadComment = original.HeadComment
+	replacement.LineComment = original.LineComment
+	replacement.FootComment = original.FootComment
+}
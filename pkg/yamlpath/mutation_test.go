@@ -0,0 +1,401 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMutation(t *testing.T) {
+	const (
+		set = iota
+		del
+		upsert
+	)
+	y := `a: 1 # one
+b:
+  - x: 1
+    y: 2
+  - x: 3
+c:
+  d: e
+`
+	cases := []struct {
+		name        string
+		input       string
+		path        string
+		operation   int
+		value       string
+		expected    string
+		expectedErr string
+		rfc9535     bool // if true, parse path using the RFC9535 option
+		aliases     bool // if true, parse path using the FollowAliases option
+		mergeKeys   bool // if true, parse path using the MergeKeys option
+		focus       bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:      "set mapping value",
+			input:     y,
+			path:      "$.a",
+			operation: set,
+			value:     "2",
+			expected: `a: 2 # one
+b:
+  - x: 1
+    y: 2
+  - x: 3
+c:
+  d: e
+`,
+		},
+		{
+			name:      "set sequence items",
+			input:     y,
+			path:      "$.b[*]",
+			operation: set,
+			value:     "z",
+			expected: `a: 1 # one
+b:
+  - z
+  - z
+c:
+  d: e
+`,
+		},
+		{
+			name:      "set with filter",
+			input:     y,
+			path:      "$.b[?(@.x > 2)].x",
+			operation: set,
+			value:     "{p: q}",
+			expected: `a: 1 # one
+b:
+  - x: 1
+    y: 2
+  - x: {p: q}
+c:
+  d: e
+`,
+		},
+		{
+			name:      "set property name renames key",
+			input:     y,
+			path:      "$.c.d~",
+			operation: set,
+			value:     "f",
+			expected: `a: 1 # one
+b:
+  - x: 1
+    y: 2
+  - x: 3
+c:
+  f: e
+`,
+		},
+		{
+			name:      "set using recursive descent",
+			input:     y,
+			path:      "$..x",
+			operation: set,
+			value:     "0",
+			expected: `a: 1 # one
+b:
+  - x: 0
+    y: 2
+  - x: 0
+c:
+  d: e
+`,
+		},
+		{
+			name:      "set root",
+			input:     "a: b\n",
+			path:      "$",
+			operation: set,
+			value:     "[1, 2]",
+			expected:  "[1, 2]\n",
+		},
+		{
+			name:      "set no match",
+			input:     y,
+			path:      "$.z",
+			operation: set,
+			value:     "0",
+			expected:  y,
+		},
+		{
+			name:      "delete mapping value",
+			input:     y,
+			path:      "$.a",
+			operation: del,
+			expected: `b:
+  - x: 1
+    y: 2
+  - x: 3
+c:
+  d: e
+`,
+		},
+		{
+			name:      "delete property name",
+			input:     y,
+			path:      "$['a','c']~",
+			operation: del,
+			expected: `b:
+  - x: 1
+    y: 2
+  - x: 3
+`,
+		},
+		{
+			name:      "delete sequence items using slice",
+			input:     "[0, 1, 2, 3, 4]\n",
+			path:      "$[1:4:2]",
+			operation: del,
+			expected:  "[0, 2, 4]\n",
+		},
+		{
+			name:      "delete duplicate matches",
+			input:     "[0, 1, 2]\n",
+			path:      "$[1,1,-2]",
+			operation: del,
+			expected:  "[0, 2]\n",
+		},
+		{
+			name:      "delete with filter",
+			input:     y,
+			path:      "$.b[?(@.y)]",
+			operation: del,
+			expected: `a: 1 # one
+b:
+  - x: 3
+c:
+  d: e
+`,
+		},
+		{
+			name:        "delete root",
+			input:       y,
+			path:        "$",
+			operation:   del,
+			expectedErr: "cannot delete the root node",
+		},
+		{
+			name:      "upsert existing child",
+			input:     y,
+			path:      "$.c.d",
+			operation: upsert,
+			value:     "f",
+			expected: `a: 1 # one
+b:
+  - x: 1
+    y: 2
+  - x: 3
+c:
+  d: f
+`,
+		},
+		{
+			name:      "upsert missing child",
+			input:     y,
+			path:      "$.b[*].y",
+			operation: upsert,
+			value:     "0",
+			expected: `a: 1 # one
+b:
+  - x: 1
+    y: 0
+  - x: 3
+    y: 0
+c:
+  d: e
+`,
+		},
+		{
+			name:      "upsert missing children and parents",
+			input:     y,
+			path:      "c.e['f g'].h",
+			operation: upsert,
+			value:     "i",
+			expected: `a: 1 # one
+b:
+  - x: 1
+    y: 2
+  - x: 3
+c:
+  d: e
+  e:
+    f g:
+      h: i
+`,
+		},
+		{
+			name:      "upsert missing bracket children",
+			input:     "a: b\n",
+			path:      "$['a','c']",
+			operation: upsert,
+			value:     "d",
+			expected:  "a: d\nc: d\n",
+		},
+		{
+			name:      "upsert not ending in child name behaves like set",
+			input:     "[0, 1]\n",
+			path:      "$[2]",
+			operation: upsert,
+			value:     "2",
+			expected:  "[0, 1]\n",
+		},
+		{
+			name:      "upsert following recursive descent behaves like set",
+			input:     "a:\n  b: 1\n",
+			path:      "$..b",
+			operation: upsert,
+			value:     "z",
+			expected:  "a:\n  b: z\n",
+		},
+		{
+			name:      "upsert following recursive descent behaves like set using RFC 9535 syntax",
+			input:     "a:\n  b: 1\n",
+			path:      "$..b",
+			operation: upsert,
+			value:     "z",
+			expected:  "a:\n  b: z\n",
+			rfc9535:   true,
+		},
+		{
+			name:      "upsert missing children and parents using RFC 9535 syntax",
+			input:     "a:\n  b: 1\n",
+			path:      "$.a['c', \"d\"].e",
+			operation: upsert,
+			value:     "f",
+			expected:  "a:\n  b: 1\n  c:\n    e: f\n  d:\n    e: f\n",
+			rfc9535:   true,
+		},
+		{
+			name:      "set via alias",
+			input:     "a: &x {b: 1}\nc: *x\n",
+			path:      "$.c.b",
+			operation: set,
+			value:     "2",
+			expected:  "a: &x {b: 2}\nc: *x\n",
+			aliases:   true,
+		},
+		{
+			name:      "set alias",
+			input:     "a: &x {b: 1}\nc: *x\n",
+			path:      "$.c",
+			operation: set,
+			value:     "d",
+			expected:  "a: &x {b: 1}\nc: d\n",
+			aliases:   true,
+		},
+		{
+			name:      "delete anchored node and alias",
+			input:     "a: [&x 1, *x, 2]\n",
+			path:      "$.a[?(@ == 1)]",
+			operation: del,
+			expected:  "a: [2]\n",
+			aliases:   true,
+		},
+		{
+			name:      "delete via alias",
+			input:     "a: &x {b: 1, c: 2}\nd: *x\n",
+			path:      "$..b",
+			operation: del,
+			expected:  "a: &x {c: 2}\nd: *x\n",
+			aliases:   true,
+		},
+		{
+			name:      "set merged value",
+			input:     "a: &x {b: 1}\nc:\n  <<: *x\n",
+			path:      "$.c.b",
+			operation: set,
+			value:     "2",
+			expected:  "a: &x {b: 2}\nc:\n  !!merge <<: *x\n", // yaml.v3 writes the merge tag explicitly
+			mergeKeys: true,
+		},
+		{
+			name:      "upsert overrides merged value",
+			input:     "a: &x {b: 1}\nc:\n  <<: *x\n",
+			path:      "$.c.b",
+			operation: upsert,
+			value:     "2",
+			expected:  "a: &x {b: 1}\nc:\n  !!merge <<: *x\n  b: 2\n",
+			mergeKeys: true,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(tc.input), &n)
+			require.NoError(t, err)
+
+			opts := []yamlpath.Option{}
+			if tc.rfc9535 {
+				opts = append(opts, yamlpath.RFC9535)
+			}
+			if tc.aliases {
+				opts = append(opts, yamlpath.FollowAliases)
+			}
+			if tc.mergeKeys {
+				opts = append(opts, yamlpath.MergeKeys)
+			}
+			p, err := yamlpath.NewPathWithOptions(tc.path, opts...)
+			require.NoError(t, err)
+
+			switch tc.operation {
+			case set, upsert:
+				var v yaml.Node
+				err = yaml.Unmarshal([]byte(tc.value), &v)
+				require.NoError(t, err)
+				if tc.operation == set {
+					err = p.Set(&n, &v)
+				} else {
+					err = p.Upsert(&n, &v)
+				}
+
+			case del:
+				err = p.Delete(&n)
+			}
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			e := yaml.NewEncoder(&buf)
+			e.SetIndent(2)
+			err = e.Encode(&n)
+			require.NoError(t, err)
+			e.Close()
+
+			require.Equal(t, tc.expected, buf.String())
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
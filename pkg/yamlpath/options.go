@@ -0,0 +1,84 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+// Option configures how NewPathWithOptions parses a path expression and how the resultant Path is applied.
+type Option func(*options)
+
+type options struct {
+	rfc9535       bool
+	documentOrder bool
+	followAliases bool
+	mergeKeys     bool
+	functions     map[string]*function // filter functions defined by WithFunction
+	documents     *string              // the document selector given by SelectDocuments, if any
+	err           error                // the first invalid option, if any
+}
+
+// RFC9535 is an Option which parses and applies path expressions strictly according to
+// RFC 9535 (https://www.rfc-editor.org/rfc/rfc9535) instead of the syntax and semantics described in the README.
+func RFC9535(o *options) {
+	o.rfc9535 = true
+}
+
+// DocumentOrder is an Option which causes each node matching a Path to be returned at most once, with the matching
+// nodes in document order, that is, in the order in which a depth-first traversal of the node to which the Path is
+// applied first reaches them (with each mapping key preceding its value). Without this option, a node may be returned
+// more than once and the order of the matching nodes depends on the Path.
+//
+// With this option, Iter and FindFirst have to find all the matching nodes before returning the first one.
+func DocumentOrder(o *options) {
+	o.documentOrder = true
+}
+
+// FollowAliases is an Option which causes a Path to be applied as if each alias node were replaced by the node
+// with the corresponding anchor, so that, for example, `$..*` matches the descendants of anchored nodes via each of
+// their aliases and a filter can refer to values in anchored nodes via aliases. A matching node is the anchored node
+// itself, so modifying it modifies the document wherever the anchor is referenced. An alias node which refers to
+// one of its own ancestors is not followed, since that would lead to a cycle.
+//
+// Since aliases can expand a small document into a very large one, FindContext should be used to apply such a
+// Path to untrusted input.
+func FollowAliases(o *options) {
+	o.followAliases = true
+}
+
+// MergeKeys is an Option which resolves merge keys (https://yaml.org/type/merge.html), such as `<<: *base`, when
+// looking up children, all the children or property names of a mapping, and in filters. The entries of each mapping
+// referred to by a merge key are treated as entries of the mapping containing the merge key, unless that mapping
+// already has an entry with the same key. If a merge key refers to a sequence of mappings, the entries of earlier
+// mappings take precedence over those of later mappings. Merge keys themselves are not matched.
+//
+// The normalized path (see FindLocations) of a merged entry passes through the merge key, for example
+// $['service']['<<']['image'], and modifying a merged entry modifies the mapping from which it was merged. However,
+// Upsert adds a missing child to the mapping containing the merge key, thereby overriding any merged entry.
+func MergeKeys(o *options) {
+	o.mergeKeys = true
+}
+
+// NewPathWithOptions constructs a Path from a string expression using the given options.
+func NewPathWithOptions(path string, opts ...Option) (*Path, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	var p *Path
+	var err error
+	if o.rfc9535 {
+		p, err = newRFC9535Path(path, o)
+	} else {
+		p, err = compile(path, o)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.withDocumentSelector(o)
+}
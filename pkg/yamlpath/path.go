@@ -7,272 +7,149 @@
 package yamlpath
 
 import (
-	"errors"
 	"strings"
 	"unicode/utf8"
 
-	"github.com/dprotaso/go-yit"
 	"gopkg.in/yaml.v3"
 )
 
 // Path is a compiled YAML path expression.
 type Path struct {
-	f func(node, root *yaml.Node) yit.Iterator
+	f    func(loc *location, root *yaml.Node) locationIterator
+	expr string  // the expression from which the Path was constructed, if constructed by NewPath or NewPathWithOptions
+	ast  *AST    // the AST from which the Path was compiled, unless the Path uses the RFC9535 option
+	opts options // the options with which the Path was constructed
+
+	documents *Path // the compiled document selector given by SelectDocuments, if any
 }
 
 // Find applies the Path to a YAML node and returns the addresses of the subnodes which match the Path.
 func (p *Path) Find(node *yaml.Node) ([]*yaml.Node, error) {
-	return p.find(node, node), nil // currently, errors are not possible
-}
-
-func (p *Path) find(node, root *yaml.Node) []*yaml.Node {
-	return p.f(node, root).ToArray()
-}
-
-// NewPath constructs a Path from a string expression.
-func NewPath(path string) (*Path, error) {
-	return newPath(lex("Path lexer", path))
+	return p.find(node).nodes(), nil // currently, errors are not possible
 }
 
-func newPath(l *lexer) (*Path, error) {
-	lx := l.nextLexeme()
-
-	switch lx.typ {
-
-	case lexemeError:
-		return nil, errors.New(lx.val)
-
-	case lexemeIdentity, lexemeEOF:
-		return new(identity), nil
-
-	case lexemeRoot:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		return new(func(node, root *yaml.Node) yit.Iterator {
-			if node.Kind == yaml.DocumentNode {
-				node = node.Content[0]
-			}
-			return compose(yit.FromNode(node), subPath, root)
-		}), nil
+// Iterator iterates over nodes in the manner of yit.Iterator: each call returns the next node and true or, when
+// there are no more nodes, nil and false.
+type Iterator func() (*yaml.Node, bool)
 
-	case lexemeRecursiveDescent:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
+// Iter applies the Path to a YAML node and returns an iterator over the subnodes which match the Path, in the same
+// order as Find. Matches are found only as the iterator is called, so the YAML node is traversed no further than
+// necessary to produce the matches which are consumed.
+func (p *Path) Iter(node *yaml.Node) Iterator {
+	next := p.find(node)
+	return func() (*yaml.Node, bool) {
+		if l, ok := next(); ok {
+			return l.node, true
 		}
-		childName := strings.TrimPrefix(lx.val, "..")
-		switch childName {
-		case "*":
-			// includes all nodes, not just mapping nodes
-			return new(func(node, root *yaml.Node) yit.Iterator {
-				return compose(yit.FromNode(node).RecurseNodes(), allChildrenThen(subPath), root)
-			}), nil
-
-		case "":
-			return new(func(node, root *yaml.Node) yit.Iterator {
-				return compose(yit.FromNode(node).RecurseNodes(), subPath, root)
-			}), nil
-
-		default:
-			return new(func(node, root *yaml.Node) yit.Iterator {
-				return compose(yit.FromNode(node).RecurseNodes(), childThen(childName, subPath), root)
-			}), nil
-		}
-
-	case lexemeDotChild:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		childName := strings.TrimPrefix(lx.val, ".")
-
-		return childThen(childName, subPath), nil
-
-	case lexemeUndottedChild:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-
-		return childThen(lx.val, subPath), nil
-
-	case lexemeBracketChild:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		childNames := strings.TrimSpace(lx.val)
-		childNames = strings.TrimSuffix(strings.TrimPrefix(childNames, "["), "]")
-		childNames = strings.TrimSpace(childNames)
-		return bracketChildThen(childNames, subPath), nil
-
-	case lexemeArraySubscript:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		subscript := strings.TrimSuffix(strings.TrimPrefix(lx.val, "["), "]")
-		return arraySubscriptThen(subscript, subPath), nil
-
-	case lexemeFilterBegin, lexemeRecursiveFilterBegin:
-		var recursive bool
-
-		if lx.typ == lexemeRecursiveFilterBegin {
-			recursive = true
-		}
-		filterLexemes := []lexeme{}
-		filterNestingLevel := 1
-	f:
-		for {
-			lx := l.nextLexeme()
-			switch lx.typ {
-			case lexemeFilterBegin:
-				filterNestingLevel++
-			case lexemeFilterEnd:
-				filterNestingLevel--
-				if filterNestingLevel == 0 {
-					break f
-				}
-			case lexemeError:
-				return nil, errors.New(lx.val)
+		return nil, false
+	}
+}
 
-			case lexemeEOF:
-				// should never happen as lexer should have detected an error
-				return nil, errors.New("missing end of filter")
-			}
-			filterLexemes = append(filterLexemes, lx)
-		}
+// FindFirst applies the Path to a YAML node and returns the first subnode which matches the Path, or nil if no
+// subnodes match. The YAML node is traversed only as far as the first match.
+func (p *Path) FindFirst(node *yaml.Node) (*yaml.Node, error) {
+	n, _ := p.Iter(node)()
+	return n, nil // currently, errors are not possible
+}
 
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		if recursive {
-			return recursiveFilterThen(filterLexemes, subPath), nil
-		}
-		return filterThen(filterLexemes, subPath), nil
-	case lexemePropertyName:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		childName := strings.TrimPrefix(lx.val, ".")
-		childName = strings.TrimSuffix(childName, propertyName)
-		return propertyNameChildThen(childName, subPath), nil
-	case lexemeBracketPropertyName:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		childNames := strings.TrimSpace(lx.val)
-		childNames = strings.TrimSuffix(childNames, propertyName)
-		childNames = strings.TrimSuffix(strings.TrimPrefix(childNames, "["), "]")
-		childNames = strings.TrimSpace(childNames)
-		return propertyNameBracketChildThen(childNames, subPath), nil
-	case lexemeArraySubscriptPropertyName:
-		subPath, err := newPath(l)
-		if err != nil {
-			return nil, err
-		}
-		subscript := strings.TrimSuffix(strings.TrimPrefix(lx.val, "["), "]~")
-		return propertyNameArraySubscriptThen(subscript, subPath), nil
-	}
+// Exists applies the Path to a YAML node and returns true if and only if some subnode matches the Path. The YAML
+// node is traversed only as far as the first match.
+func (p *Path) Exists(node *yaml.Node) (bool, error) {
+	_, ok := p.Iter(node)()
+	return ok, nil // currently, errors are not possible
+}
 
-	return nil, errors.New("invalid path syntax")
+func (p *Path) find(node *yaml.Node) locationIterator {
+	return p.findFrom(&location{node: node})
 }
 
-func identity(node, root *yaml.Node) yit.Iterator {
-	if node.Kind == 0 {
-		return yit.FromNodes()
+// findFrom applies the Path to the node at the given location, which has no parent.
+func (p *Path) findFrom(loc *location) locationIterator {
+	loc.followAliases = p.opts.followAliases
+	loc.mergeKeys = p.opts.mergeKeys
+	next := p.f(loc, loc.node)
+	if p.opts.documentOrder {
+		return next.inDocumentOrder()
 	}
-	return yit.FromNode(node)
+	return next
 }
 
-func empty(node, root *yaml.Node) yit.Iterator {
-	return yit.FromNodes()
+// NewPath constructs a Path from a string expression.
+func NewPath(path string) (*Path, error) {
+	return NewPathWithOptions(path)
 }
 
-func compose(i yit.Iterator, p *Path, root *yaml.Node) yit.Iterator {
-	its := []yit.Iterator{}
-	for a, ok := i(); ok; a, ok = i() {
-		its = append(its, p.f(a, root))
+// compile constructs a Path from a string expression in the syntax described in the README.
+func compile(path string, o *options) (*Path, error) {
+	ast, err := parse(path, o)
+	if err != nil {
+		return nil, err
+	}
+	p, err := compileAST(ast, o)
+	if err != nil {
+		return nil, err
 	}
-	return yit.FromIterators(its...)
+	p.expr = path
+	p.ast = ast
+	p.opts = *o
+	return p, nil
 }
 
-func new(f func(node, root *yaml.Node) yit.Iterator) *Path {
-	return &Path{f: f}
+func identity(loc *location, root *yaml.Node) locationIterator {
+	if loc.node.Kind == 0 {
+		return fromLocations()
+	}
+	return fromLocations(loc)
 }
 
-func propertyNameChildThen(childName string, p *Path) *Path {
-	childName = unescape(childName)
+func empty(loc *location, root *yaml.Node) locationIterator {
+	return fromLocations()
+}
 
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
-		}
-		for i, n := range node.Content {
-			if i%2 == 0 && n.Value == childName {
-				return compose(yit.FromNode(node.Content[i]), p, root)
+// compose applies p to each location produced by i and iterates over the results in order. Locations are taken
+// from i only as the results are consumed.
+func compose(i locationIterator, p *Path, root *yaml.Node) locationIterator {
+	var current locationIterator
+	return func() (*location, bool) {
+		for {
+			if current != nil {
+				if l, ok := current(); ok {
+					return l, true
+				}
 			}
+			a, ok := i()
+			if !ok {
+				return nil, false
+			}
+			current = p.f(a, root)
 		}
-		return empty(node, root)
-	})
+	}
 }
 
-func propertyNameBracketChildThen(childNames string, p *Path) *Path {
-	unquotedChildren := bracketChildNames(childNames)
+func new(f func(loc *location, root *yaml.Node) locationIterator) *Path {
+	return &Path{f: f}
+}
 
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+// propertyNamesThen matches the keys with the given names, which are unescaped, and applies p to them.
+func propertyNamesThen(childNames []string, p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind != yaml.MappingNode {
+			return empty(loc, root)
 		}
-		its := []yit.Iterator{}
-		for _, childName := range unquotedChildren {
-			for i, n := range node.Content {
-				if i%2 == 0 && n.Value == childName {
-					its = append(its, yit.FromNode(node.Content[i]))
-				}
-			}
+		its := []locationIterator{}
+		for _, e := range loc.namedEntries(childNames) {
+			its = append(its, fromLocations(e.key()))
 		}
-		return compose(yit.FromIterators(its...), p, root)
+		return compose(fromLocationIterators(its...), p, root)
 	})
 }
 
 func propertyNameArraySubscriptThen(subscript string, p *Path) *Path {
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		if node.Kind == yaml.MappingNode && subscript == "*" {
-			its := []yit.Iterator{}
-			for i, n := range node.Content {
-				if i%2 != 0 {
-					continue // skip child values
-				}
-				its = append(its, compose(yit.FromNode(n), p, root))
-			}
-			return yit.FromIterators(its...)
-		}
-		return empty(node, root)
-	})
-}
-
-func childThen(childName string, p *Path) *Path {
-	if childName == "*" {
-		return allChildrenThen(p)
-	}
-	childName = unescape(childName)
-
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind == yaml.MappingNode && subscript == "*" {
+			return compose(fromLocations(loc).keys(), p, root)
 		}
-		for i, n := range node.Content {
-			if i%2 == 0 && n.Value == childName {
-				return compose(yit.FromNode(node.Content[i+1]), p, root)
-			}
-		}
-		return empty(node, root)
+		return empty(loc, root)
 	})
 }
 
@@ -335,22 +212,17 @@ func balanced(c string, q rune) bool {
 	return bal
 }
 
-func bracketChildThen(childNames string, p *Path) *Path {
-	unquotedChildren := bracketChildNames(childNames)
-
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+// childrenThen matches the values of the keys with the given names, which are unescaped, and applies p to them.
+func childrenThen(childNames []string, p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind != yaml.MappingNode {
+			return empty(loc, root)
 		}
-		its := []yit.Iterator{}
-		for _, childName := range unquotedChildren {
-			for i, n := range node.Content {
-				if i%2 == 0 && n.Value == childName {
-					its = append(its, yit.FromNode(node.Content[i+1]))
-				}
-			}
+		its := []locationIterator{}
+		for _, e := range loc.namedEntries(childNames) {
+			its = append(its, fromLocations(e.value()))
 		}
-		return compose(yit.FromIterators(its...), p, root)
+		return compose(fromLocationIterators(its...), p, root)
 	})
 }
 
@@ -375,89 +247,97 @@ func unescape(raw string) string {
 }
 
 func allChildrenThen(p *Path) *Path {
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		switch node.Kind {
-		case yaml.MappingNode:
-			its := []yit.Iterator{}
-			for i, n := range node.Content {
-				if i%2 == 0 {
-					continue // skip child names
-				}
-				its = append(its, compose(yit.FromNode(n), p, root))
-			}
-			return yit.FromIterators(its...)
-
-		case yaml.SequenceNode:
-			its := []yit.Iterator{}
-			for i := 0; i < len(node.Content); i++ {
-				its = append(its, compose(yit.FromNode(node.Content[i]), p, root))
-			}
-			return yit.FromIterators(its...)
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		return compose(fromLocations(loc).values(), p, root)
+	})
+}
 
-		default:
-			return empty(node, root)
+// arraySubscriptThen matches the items of a sequence node selected by the given subscripts and applies p to them.
+func arraySubscriptThen(subscripts []Subscript, p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind != yaml.SequenceNode {
+			return empty(loc, root)
 		}
+		return compose(fromLocations(loc).indices(func(length int) indexIterator {
+			return subscriptIndices(subscripts, length)
+		}), p, root)
 	})
 }
 
-func arraySubscriptThen(subscript string, p *Path) *Path {
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		if node.Kind == yaml.MappingNode && subscript == "*" {
-			its := []yit.Iterator{}
-			for i, n := range node.Content {
-				if i%2 == 0 {
-					continue // skip child names
-				}
-				its = append(its, compose(yit.FromNode(n), p, root))
-			}
-			return yit.FromIterators(its...)
-		}
-		if node.Kind != yaml.SequenceNode {
-			return empty(node, root)
+// unionThen applies each of the given paths to the node in turn and concatenates the results. Each path matches a
+// member of a union and then applies the remainder of the path.
+func unionThen(members []*Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		its := []locationIterator{}
+		for _, m := range members {
+			its = append(its, m.f(loc, root))
 		}
+		return fromLocationIterators(its...)
+	})
+}
 
-		slice, err := slice(subscript, len(node.Content))
-		if err != nil {
-			panic(err) // should not happen, lexer should have detected errors
+// tagThen matches the node if it has the given tag and applies p to it.
+func tagThen(tag string, p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.ShortTag() != tag {
+			return empty(loc, root)
 		}
+		return compose(fromLocations(loc), p, root)
+	})
+}
 
-		its := []yit.Iterator{}
-		for _, s := range slice {
-			if s >= 0 && s < len(node.Content) {
-				its = append(its, compose(yit.FromNode(node.Content[s]), p, root))
-			}
+// parentThen matches the parent of the node and applies p to it.
+func parentThen(p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		return compose(fromLocations(loc).parents(), p, root)
+	})
+}
+
+// ancestorsThen matches the ancestors of the node, nearest first, and applies p to them.
+func ancestorsThen(p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		return compose(fromLocations(loc).ancestors(), p, root)
+	})
+}
+
+// filterThen matches the items of a sequence node, or the node itself if it is not a sequence, which satisfy the
+// filter and applies p to them. If the filter refers to the key of the node being filtered, it is applied to the
+// values of a mapping node rather than to the mapping itself.
+func filterThen(parseTree *filterNode, p *Path) *Path {
+	if parseTree.refersToKey() {
+		return entryFilterThen(parseTree, p)
+	}
+	filter := newFilter(parseTree)
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind == yaml.SequenceNode {
+			return compose(fromLocations(loc).values().filter(filter, root), p, root)
 		}
-		return yit.FromIterators(its...)
+		return compose(fromLocations(loc).filter(filter, root), p, root)
 	})
 }
 
-func filterThen(filterLexemes []lexeme, p *Path) *Path {
-	filter := newFilter(newFilterNode(filterLexemes))
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		its := []yit.Iterator{}
-		if node.Kind == yaml.SequenceNode {
-			for _, c := range node.Content {
-				if filter(c, root) {
-					its = append(its, compose(yit.FromNode(c), p, root))
-				}
-			}
-		} else {
-			if filter(node, root) {
-				its = append(its, compose(yit.FromNode(node), p, root))
-			}
+// entryFilterThen matches the values of a mapping node, the items of a sequence node, or the node itself if it is
+// neither, which satisfy the filter and applies p to them.
+func entryFilterThen(parseTree *filterNode, p *Path) *Path {
+	filter := newFilter(parseTree)
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind == yaml.MappingNode || loc.node.Kind == yaml.SequenceNode {
+			return compose(fromLocations(loc).values().filter(filter, root), p, root)
 		}
-		return yit.FromIterators(its...)
+		return compose(fromLocations(loc).filter(filter, root), p, root)
 	})
 }
 
-func recursiveFilterThen(filterLexemes []lexeme, p *Path) *Path {
-	filter := newFilter(newFilterNode(filterLexemes))
-	return new(func(node, root *yaml.Node) yit.Iterator {
-		its := []yit.Iterator{}
+// keyThen matches the key of the node, if it is a mapping value, and applies p to it.
+func keyThen(p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		return compose(fromLocations(loc).valueKeys(), p, root)
+	})
+}
 
-		if filter(node, root) {
-			its = append(its, compose(yit.FromNode(node), p, root))
-		}
-		return yit.FromIterators(its...)
+func recursiveFilterThen(parseTree *filterNode, p *Path) *Path {
+	filter := newFilter(parseTree)
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		return compose(fromLocations(loc).filter(filter, root), p, root)
 	})
 }
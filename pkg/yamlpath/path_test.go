@@ -122,6 +122,75 @@ test: this is a test
 			path: "$.test~",
 			expectedStrings: []string{
 				`test
+`,
+			},
+			expectedPathErr: "",
+		},
+		{
+			name: "property names of wildcard",
+			path: "$.store.bicycle.*~",
+			expectedStrings: []string{
+				`color
+`,
+				`price
+`,
+			},
+			expectedPathErr: "",
+		},
+		{
+			name: "property names after recursive descent",
+			path: "$..isbn~",
+			expectedStrings: []string{
+				`isbn
+`,
+				`isbn
+`,
+			},
+			expectedPathErr: "",
+		},
+		{
+			name: "property names of wildcard after recursive descent",
+			path: "$.store..bicycle.*~",
+			expectedStrings: []string{
+				`color
+`,
+				`price
+`,
+			},
+			expectedPathErr: "",
+		},
+		{
+			name: "property names followed by filter",
+			path: "$.store.bicycle.*~[?(@ =~ /^c/)]",
+			expectedStrings: []string{
+				`color
+`,
+			},
+			expectedPathErr: "",
+		},
+		{
+			name: "property names followed by parent",
+			path: "$.store.book[*].isbn~^.title",
+			expectedStrings: []string{
+				`Moby Dick
+`,
+				`The Lord of the Rings
+`,
+			},
+			expectedPathErr: "",
+		},
+		{
+			name:            "property names followed by child",
+			path:            "$.store~.book",
+			expectedStrings: []string{},
+			expectedPathErr: "",
+		},
+		{
+			name: "property names in filter",
+			path: "$.store.*[?(@.color~)]",
+			expectedStrings: []string{
+				`color: red
+price: 19.95
 `,
 			},
 			expectedPathErr: "",
@@ -860,9 +929,15 @@ price: 22.99
 		},
 		{
 			name:            "malformed array subscript",
+			path:            "$.store.book[1:2:3:4]",
+			expectedStrings: []string{},
+			expectedPathErr: "invalid array index [1:2:3:4] before position 21: malformed array index, too many colons",
+		},
+		{
+			name:            "array slice with zero step",
 			path:            "$.store.book[::0]",
 			expectedStrings: []string{},
-			expectedPathErr: "invalid array index [::0] before position 17: array index step value must be non-zero",
+			expectedPathErr: "",
 		},
 		{
 			name:            "array subscript out of bounds",
@@ -969,6 +1044,96 @@ price: 12.99
 			},
 			expectedPathErr: "",
 		},
+		{
+			name: "filter with length function",
+			path: "$.store.book[?(length(@.title) > 15)].title",
+			expectedStrings: []string{
+				"Sayings of the Century\n",
+				"The Lord of the Rings\n",
+			},
+		},
+		{
+			name: "filter with count function",
+			path: "$.store.book[?(count(@.*) == 5)].title",
+			expectedStrings: []string{
+				"Moby Dick\n",
+				"The Lord of the Rings\n",
+			},
+		},
+		{
+			name: "filter with match and search functions",
+			path: "$.store.book[?(match(@.isbn, '0-[0-9-]+') && !search(@.author, 'Tolkien'))].title",
+			expectedStrings: []string{
+				"Moby Dick\n",
+			},
+		},
+		{
+			name: "filter with value function",
+			path: "$.x[?(value(@..z) == 3)].y[0].w",
+			expectedStrings: []string{
+				"4\n",
+			},
+		},
+		{
+			name: "filter with function on right hand side of comparison",
+			path: "$.store.book[?(9 == length(@.title))].title",
+			expectedStrings: []string{
+				"Moby Dick\n",
+			},
+		},
+		{
+			name:            "filter with uncompared function result",
+			path:            "$.store.book[?(length(@.title))]",
+			expectedPathErr: "result of function length() must be compared",
+		},
+		{
+			name:            "filter with non-singular function argument",
+			path:            "$.store.book[?(length(@.*) > 1)]",
+			expectedPathErr: "argument 1 of function length(): query must be singular",
+		},
+		{
+			name:            "filter with unknown function",
+			path:            "$.store.book[?(foo(@.title))]",
+			expectedPathErr: `unknown function foo() at position 18, following "[?(foo"`,
+		},
+		{
+			name:            "filter with argument separator outside function",
+			path:            "$.store.book[?(@.title, @.author)]",
+			expectedPathErr: `unexpected "," outside function arguments`,
+		},
+		{
+			name:            "nested filter with invalid function call",
+			path:            "$.store[?(@.book[?(match(@.title))])]",
+			expectedPathErr: "function match() takes 2 argument(s)",
+		},
+		{
+			name: "filter with arithmetic",
+			path: "$.store.book[?(@.price * 2 > $.store.bicycle.price + 5)].title",
+			expectedStrings: []string{
+				"Sword of Honour\n",
+				"The Lord of the Rings\n",
+			},
+		},
+		{
+			name: "filter with arithmetic on current node",
+			path: "$.store.book[*].price[?(@ - 0.99 == 8 || -@ < -20)]",
+			expectedStrings: []string{
+				"8.99\n",
+				"22.99\n",
+			},
+		},
+		{
+			name: "filter with arithmetic function argument",
+			path: "$.store.book[?(length(@.category + ': ' + @.title) < 20)].title",
+			expectedStrings: []string{
+				"Moby Dick\n",
+			},
+		},
+		{
+			name:            "filter with uncompared arithmetic",
+			path:            "$.store.book[?(@.price * 2)]",
+			expectedPathErr: "result of arithmetic expression must be compared",
+		},
 		{
 			name: "map filter",
 			path: `$.store.bicycle[?(@.color == "red")]`,
@@ -1096,10 +1261,10 @@ another: entry`,
 			expectedStrings: []string{"{\"key\": 500}\n", "{\"key\": 600}\n"},
 		},
 		{
-			name:            "union with wildcard and numbers (deviation from comparison project consensus)",
+			name:            "union with wildcard and numbers",
 			input:           `["a","b","c"]`,
 			path:            `$[*,1,0,*]`,
-			expectedPathErr: `invalid array index [*,1,0,*] before position 10: error in union member 0: wildcard cannot be used in union`,
+			expectedStrings: []string{"\"a\"\n", "\"b\"\n", "\"c\"\n", "\"b\"\n", "\"a\"\n", "\"a\"\n", "\"b\"\n", "\"c\"\n"},
 		},
 		{
 			name:            "special characters in bracket child name",
@@ -1202,3 +1367,982 @@ another: entry`,
 		t.Fatalf("testcase(s) still focussed")
 	}
 }
+
+func TestFindFirst(t *testing.T) {
+	input := `a:
+  - b: 1
+  - b: 2
+  - c: 3
+d:
+  b: 4
+`
+
+	cases := []struct {
+		name             string
+		path             string
+		options          []yamlpath.Option
+		expectedFirst    string
+		expectedIterated []string
+		expectedCalls    int  // number of times the counting function is called by FindFirst, if non-zero
+		focus            bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:             "no match",
+			path:             "$.x",
+			expectedIterated: []string{},
+		},
+		{
+			name:             "single match",
+			path:             "$.d.b",
+			expectedFirst:    "4\n",
+			expectedIterated: []string{"4\n"},
+		},
+		{
+			name:             "several matches",
+			path:             "$..b",
+			expectedFirst:    "1\n",
+			expectedIterated: []string{"1\n", "2\n", "4\n"},
+		},
+		{
+			name:             "recursive descent with wildcard",
+			path:             "$..*",
+			expectedFirst:    "- b: 1\n- b: 2\n- c: 3\n",
+			expectedIterated: []string{"- b: 1\n- b: 2\n- c: 3\n", "b: 4\n", "b: 1\n", "b: 2\n", "c: 3\n", "1\n", "2\n", "3\n", "4\n"},
+		},
+		{
+			name:             "filter stops at first match",
+			path:             "$.a[?(counted(@.b))].b",
+			expectedFirst:    "1\n",
+			expectedIterated: []string{"1\n", "2\n"},
+			expectedCalls:    1,
+		},
+		{
+			name:             "recursive filter stops at first match",
+			path:             "$..[?(counted(@.b))]",
+			expectedFirst:    "b: 1\n",
+			expectedIterated: []string{"b: 1\n", "b: 2\n", "b: 4\n"},
+			expectedCalls:    4, // the filter is applied to the root, the key "a", and the sequence before the first item
+		},
+		{
+			name:             "RFC 9535 filter stops at first match",
+			path:             "$.a[?counted(@.b)].b",
+			options:          []yamlpath.Option{yamlpath.RFC9535},
+			expectedFirst:    "1\n",
+			expectedIterated: []string{"1\n", "2\n"},
+			expectedCalls:    1,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			calls := 0
+			counted := yamlpath.WithFunction("counted", yamlpath.FunctionSignature{
+				Params: []yamlpath.FunctionType{yamlpath.NodesType},
+				Result: yamlpath.LogicalType,
+			}, func(args []yamlpath.FunctionValue) yamlpath.FunctionValue {
+				calls++
+				return yamlpath.FunctionValue{Logical: len(args[0].Nodes) > 0}
+			})
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, append(tc.options, counted)...)
+			require.NoError(t, err)
+
+			encode := func(n *yaml.Node) string {
+				var buf bytes.Buffer
+				e := yaml.NewEncoder(&buf)
+				e.SetIndent(2)
+				err := e.Encode(n)
+				require.NoError(t, err)
+				e.Close()
+				return buf.String()
+			}
+
+			first, err := p.FindFirst(&n)
+			require.NoError(t, err)
+			if tc.expectedFirst == "" {
+				require.Nil(t, first)
+			} else {
+				require.Equal(t, tc.expectedFirst, encode(first))
+			}
+			if tc.expectedCalls != 0 {
+				require.Equal(t, tc.expectedCalls, calls)
+			}
+
+			exists, err := p.Exists(&n)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedFirst != "", exists)
+
+			actualStrings := []string{}
+			it := p.Iter(&n)
+			for a, ok := it(); ok; a, ok = it() {
+				actualStrings = append(actualStrings, encode(a))
+			}
+			require.Equal(t, tc.expectedIterated, actualStrings)
+
+			found, err := p.Find(&n)
+			require.NoError(t, err)
+			require.Len(t, found, len(tc.expectedIterated))
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestDocumentOrder(t *testing.T) {
+	input := `a:
+  - x: 1
+  - x: 2
+b:
+  x: 3
+  c:
+    x: 4
+`
+
+	cases := []struct {
+		name            string
+		path            string
+		options         []yamlpath.Option
+		expectedStrings []string
+		focus           bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:            "union with duplicate indices",
+			path:            "$.a[1,0,1]",
+			expectedStrings: []string{"x: 1\n", "x: 2\n"},
+		},
+		{
+			name:            "bracket child names out of order",
+			path:            "$['b','a','b'].x",
+			expectedStrings: []string{"3\n"},
+		},
+		{
+			name:            "overlapping recursive descent",
+			path:            "$..x",
+			expectedStrings: []string{"1\n", "2\n", "3\n", "4\n"},
+		},
+		{
+			name:            "recursive descent of recursive descent",
+			path:            "$..*..x",
+			expectedStrings: []string{"1\n", "2\n", "3\n", "4\n"},
+		},
+		{
+			name:            "ancestor before descendants",
+			path:            "$..[?(@.x)]",
+			expectedStrings: []string{"x: 1\n", "x: 2\n", "x: 3\nc:\n  x: 4\n", "x: 4\n"},
+		},
+		{
+			name:            "RFC 9535 union",
+			path:            "$.a[1,0,-1]",
+			options:         []yamlpath.Option{yamlpath.RFC9535},
+			expectedStrings: []string{"x: 1\n", "x: 2\n"},
+		},
+		{
+			name:            "RFC 9535 descendants",
+			path:            "$..[*,0].x",
+			options:         []yamlpath.Option{yamlpath.RFC9535},
+			expectedStrings: []string{"1\n", "2\n", "3\n", "4\n"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, append(tc.options, yamlpath.DocumentOrder)...)
+			require.NoError(t, err)
+
+			actual, err := p.Find(&n)
+			require.NoError(t, err)
+
+			actualStrings := []string{}
+			for _, a := range actual {
+				var buf bytes.Buffer
+				e := yaml.NewEncoder(&buf)
+				e.SetIndent(2)
+
+				err = e.Encode(a)
+				require.NoError(t, err)
+				e.Close()
+				actualStrings = append(actualStrings, buf.String())
+			}
+
+			require.Equal(t, tc.expectedStrings, actualStrings)
+
+			locations, err := p.FindLocations(&n)
+			require.NoError(t, err)
+			require.Len(t, locations, len(actual))
+			for i, l := range locations {
+				require.Equal(t, actual[i], l.Node)
+			}
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestAliases(t *testing.T) {
+	input := `defaults: &defaults
+  pull: always
+  ports: [80]
+web: *defaults
+worker:
+  <<: *defaults
+  pull: never
+loop: &loop
+  self: *loop
+`
+
+	cases := []struct {
+		name          string
+		path          string
+		options       []yamlpath.Option
+		expectedPaths []string // the normalized paths of the matching nodes
+		focus         bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name:          "aliases not followed",
+			path:          "$..pull",
+			expectedPaths: []string{"$['defaults']['pull']", "$['worker']['pull']"},
+		},
+		{
+			name:          "aliases followed by recursive descent",
+			path:          "$..pull",
+			options:       []yamlpath.Option{yamlpath.FollowAliases},
+			expectedPaths: []string{"$['defaults']['pull']", "$['web']['pull']", "$['worker']['pull']", "$['worker']['<<']['pull']"},
+		},
+		{
+			name:          "aliases followed by child",
+			path:          "$.web.ports[0]",
+			options:       []yamlpath.Option{yamlpath.FollowAliases},
+			expectedPaths: []string{"$['web']['ports'][0]"},
+		},
+		{
+			name:          "aliases followed in filter",
+			path:          "$.*[?(@.pull == 'always')]",
+			options:       []yamlpath.Option{yamlpath.FollowAliases},
+			expectedPaths: []string{"$['defaults']", "$['web']"},
+		},
+		{
+			name:          "aliases followed with RFC 9535",
+			path:          "$.*.pull",
+			options:       []yamlpath.Option{yamlpath.RFC9535, yamlpath.FollowAliases},
+			expectedPaths: []string{"$['defaults']['pull']", "$['web']['pull']", "$['worker']['pull']"},
+		},
+		{
+			name:          "cycle not followed",
+			path:          "$.loop..*",
+			options:       []yamlpath.Option{yamlpath.FollowAliases},
+			expectedPaths: []string{"$['loop']['self']"},
+		},
+		{
+			name:          "anchors",
+			path:          "$..[?(anchor(@) == 'defaults')]",
+			expectedPaths: []string{"$['defaults']"},
+		},
+		{
+			name:          "anchors via aliases",
+			path:          "$.*[?(anchor(@) == 'defaults')]",
+			options:       []yamlpath.Option{yamlpath.FollowAliases},
+			expectedPaths: []string{"$['defaults']", "$['web']"},
+		},
+		{
+			name:          "aliases",
+			path:          "$..[?(alias(@) == 'defaults')]",
+			expectedPaths: []string{"$['web']", "$['worker']['<<']"},
+		},
+		{
+			name:          "aliases with RFC 9535",
+			path:          "$..[?alias(@) == 'loop']",
+			options:       []yamlpath.Option{yamlpath.RFC9535},
+			expectedPaths: []string{"$['loop']['self']"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			locations, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualPaths := []string{}
+			for _, l := range locations {
+				actualPaths = append(actualPaths, l.Path)
+			}
+			require.Equal(t, tc.expectedPaths, actualPaths)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestTags(t *testing.T) {
+	input := `Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${AWS::StackName}-data"
+      Tags: [{Key: owner, Value: !Ref Owner}]
+  Policy:
+    Properties:
+      Bucket: !Ref Bucket
+      Arn: !GetAtt Bucket.Arn
+      Count: 2
+`
+
+	cases := []struct {
+		name            string
+		path            string
+		options         []yamlpath.Option
+		expectedStrings []string // the values of the matching nodes
+		focus           bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name:            "tag selector",
+			path:            "$..[!Ref]",
+			expectedStrings: []string{"Owner", "Bucket"},
+		},
+		{
+			name:            "tag selector after child",
+			path:            "$.Resources.Policy.Properties.*[!GetAtt]",
+			expectedStrings: []string{"Bucket.Arn"},
+		},
+		{
+			name:            "tag selector for implicit tag",
+			path:            "$..[!!int]",
+			expectedStrings: []string{"2"},
+		},
+		{
+			name:            "tag selector in filter",
+			path:            "$.Resources.*[?(@.Properties.BucketName[!Sub])].Type",
+			expectedStrings: []string{"AWS::S3::Bucket"},
+		},
+		{
+			name:            "tag function",
+			path:            "$.Resources.Policy.Properties[?(tag(@.Arn) == '!GetAtt')].Bucket",
+			expectedStrings: []string{"Bucket"},
+		},
+		{
+			name:            "tag function with RFC 9535",
+			path:            "$.Resources.Policy.Properties[?tag(@) == '!Ref']",
+			options:         []yamlpath.Option{yamlpath.RFC9535},
+			expectedStrings: []string{"Bucket"},
+		},
+		{
+			name:            "kind function",
+			path:            "$.Resources.Bucket.Properties.*[?(kind(@) == 'mapping')].Key",
+			expectedStrings: []string{"owner"},
+		},
+		{
+			name:            "kind function of missing node",
+			path:            "$.Resources.*[?(kind(@.Type) == 'scalar')].Type",
+			expectedStrings: []string{"AWS::S3::Bucket"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			actual, err := p.Find(&n)
+			require.NoError(t, err)
+
+			actualStrings := []string{}
+			for _, a := range actual {
+				actualStrings = append(actualStrings, a.Value)
+			}
+			require.Equal(t, tc.expectedStrings, actualStrings)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestParents(t *testing.T) {
+	input := `spec:
+  containers:
+  - name: web
+    ports:
+    - containerPort: 8080
+  - name: sidecar
+    ports:
+    - containerPort: 9090
+    - containerPort: 8080
+`
+
+	cases := []struct {
+		name          string
+		path          string
+		options       []yamlpath.Option
+		expectedPaths []string // the normalized paths of the matching nodes
+		focus         bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name:          "parent",
+			path:          "$.spec.containers[0].name^",
+			expectedPaths: []string{"$['spec']['containers'][0]"},
+		},
+		{
+			name:          "parent of root",
+			path:          "$^",
+			expectedPaths: []string{},
+		},
+		{
+			name: "parents after filter",
+			path: "$.spec.containers[*].ports[?(@.containerPort == 8080)]^^.name",
+			expectedPaths: []string{
+				"$['spec']['containers'][0]['name']",
+				"$['spec']['containers'][1]['name']",
+			},
+		},
+		{
+			name:          "parents after recursive descent",
+			path:          "$..[?(@.containerPort == 9090)]^^.name",
+			expectedPaths: []string{"$['spec']['containers'][1]['name']"},
+		},
+		{
+			name: "parent in filter",
+			path: "$.spec.containers[*].ports[*][?(@^^.name == 'sidecar')].containerPort",
+			expectedPaths: []string{
+				"$['spec']['containers'][1]['ports'][0]['containerPort']",
+				"$['spec']['containers'][1]['ports'][1]['containerPort']",
+			},
+		},
+		{
+			name: "ancestors",
+			path: "$.spec.containers[1].ports[0].containerPort^*",
+			expectedPaths: []string{
+				"$['spec']['containers'][1]['ports'][0]",
+				"$['spec']['containers'][1]['ports']",
+				"$['spec']['containers'][1]",
+				"$['spec']['containers']",
+				"$['spec']",
+				"$",
+			},
+		},
+		{
+			name: "ancestors with filter",
+			path: "$..containerPort^*.name",
+			expectedPaths: []string{
+				"$['spec']['containers'][0]['name']",
+				"$['spec']['containers'][1]['name']",
+				"$['spec']['containers'][1]['name']",
+			},
+		},
+		{
+			name:    "ancestors in document order",
+			path:    "$..containerPort^*.name",
+			options: []yamlpath.Option{yamlpath.DocumentOrder},
+			expectedPaths: []string{
+				"$['spec']['containers'][0]['name']",
+				"$['spec']['containers'][1]['name']",
+			},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			locations, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualPaths := []string{}
+			for _, l := range locations {
+				actualPaths = append(actualPaths, l.Path)
+			}
+			require.Equal(t, tc.expectedPaths, actualPaths)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestKeyFilters(t *testing.T) {
+	input := `metadata:
+  labels:
+    app.kubernetes.io/name: web
+    app.kubernetes.io/part-of: shop
+    tier: frontend
+    canary: 'true'
+  annotations:
+    app.kubernetes.io/managed-by: helm
+spec:
+  args: [--verbose]
+`
+
+	cases := []struct {
+		name          string
+		path          string
+		expectedPaths []string // the normalized paths of the matching nodes
+		focus         bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name: "values whose keys match",
+			path: `$.metadata.labels[?(@~ =~ /^app\.kubernetes\.io\//)]`,
+			expectedPaths: []string{
+				"$['metadata']['labels']['app.kubernetes.io/name']",
+				"$['metadata']['labels']['app.kubernetes.io/part-of']",
+			},
+		},
+		{
+			name: "keys which match",
+			path: `$.metadata.labels[?(@~ =~ /^app\.kubernetes\.io\//)]~`,
+			expectedPaths: []string{
+				"$['metadata']['labels']['app.kubernetes.io/name']~",
+				"$['metadata']['labels']['app.kubernetes.io/part-of']~",
+			},
+		},
+		{
+			name:          "keys whose values match",
+			path:          "$.metadata.labels[?(@ == 'true')]~",
+			expectedPaths: []string{"$['metadata']['labels']['canary']~"},
+		},
+		{
+			name:          "key and value",
+			path:          "$.metadata.labels[?(@~ =~ /name$/ && @ == 'web')]",
+			expectedPaths: []string{"$['metadata']['labels']['app.kubernetes.io/name']"},
+		},
+		{
+			name:          "key function argument",
+			path:          "$.metadata.labels[?(length(@~) < 5)]~",
+			expectedPaths: []string{"$['metadata']['labels']['tier']~"},
+		},
+		{
+			name:          "key filter then child",
+			path:          "$.metadata[?(@~ == 'labels')].tier",
+			expectedPaths: []string{"$['metadata']['labels']['tier']"},
+		},
+		{
+			name:          "key of scalar",
+			path:          "$.metadata.labels.tier[?(@ == 'frontend')]~",
+			expectedPaths: []string{"$['metadata']['labels']['tier']~"},
+		},
+		{
+			name:          "sequence items have no keys",
+			path:          "$.spec.args[?(@~)]",
+			expectedPaths: []string{},
+		},
+		{
+			name: "recursive descent",
+			path: "$..[?(@~ =~ /^app/)]~",
+			expectedPaths: []string{
+				"$['metadata']['labels']['app.kubernetes.io/name']~",
+				"$['metadata']['labels']['app.kubernetes.io/part-of']~",
+				"$['metadata']['annotations']['app.kubernetes.io/managed-by']~",
+			},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPath(tc.path)
+			require.NoError(t, err)
+
+			locations, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualPaths := []string{}
+			for _, l := range locations {
+				actualPaths = append(actualPaths, l.Path)
+			}
+			require.Equal(t, tc.expectedPaths, actualPaths)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestUnions(t *testing.T) {
+	input := `name: shop
+ports:
+  - {name: http, port: 80}
+  - {name: https, port: 443}
+  - {name: metrics, port: 9090, internal: true}
+  - {name: debug, port: 6060, internal: true}
+`
+
+	cases := []struct {
+		name          string
+		path          string
+		expectedPaths []string // the normalized paths of the matching nodes
+		focus         bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name: "indices, slices, and filters",
+			path: "$.ports[0, 2:4, ?(@.port > 400)].name",
+			expectedPaths: []string{
+				"$['ports'][0]['name']",
+				"$['ports'][2]['name']",
+				"$['ports'][3]['name']",
+				"$['ports'][1]['name']",
+				"$['ports'][2]['name']",
+				"$['ports'][3]['name']",
+			},
+		},
+		{
+			name:          "names and wildcard",
+			path:          "$['name', *]",
+			expectedPaths: []string{"$['name']", "$['name']", "$['ports']"},
+		},
+		{
+			name:          "names and indices",
+			path:          "$['name', 0]",
+			expectedPaths: []string{"$['name']"},
+		},
+		{
+			name:          "filter first",
+			path:          "$.ports[?(@.internal), -1:].port",
+			expectedPaths: []string{"$['ports'][2]['port']", "$['ports'][3]['port']", "$['ports'][3]['port']"},
+		},
+		{
+			name:          "filters",
+			path:          "$.ports[?(@.name == 'debug'), ?(@.port == 80)].port",
+			expectedPaths: []string{"$['ports'][3]['port']", "$['ports'][0]['port']"},
+		},
+		{
+			name:          "in filter",
+			path:          "$.ports[?(@[?(@~ == 'port' && @ == 443), 'internal'])].name",
+			expectedPaths: []string{"$['ports'][1]['name']", "$['ports'][2]['name']", "$['ports'][3]['name']"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPath(tc.path)
+			require.NoError(t, err)
+
+			locations, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualPaths := []string{}
+			for _, l := range locations {
+				actualPaths = append(actualPaths, l.Path)
+			}
+			require.Equal(t, tc.expectedPaths, actualPaths)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `# the deployment
+spec:
+  # renovate: datasource=docker
+  image: nginx:1.19
+  sidecar: envoy:1.16 # renovate: datasource=docker
+  replicas: 1 # fixed
+  ports:
+    # the only port
+    - 80
+`
+
+	cases := []struct {
+		name            string
+		path            string
+		options         []yamlpath.Option
+		expectedStrings []string // the values of the matching nodes
+		focus           bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name:            "comments of keys and values",
+			path:            "$.spec.*[?(comment(@) =~ /renovate/)]",
+			expectedStrings: []string{"nginx:1.19", "envoy:1.16"},
+		},
+		{
+			name:            "comment of sequence item",
+			path:            "$.spec.ports[?(comment(@) == '# the only port')]",
+			expectedStrings: []string{"80"},
+		},
+		{
+			name:            "comment of child",
+			path:            "$.spec[?(comment(@.replicas) == '# fixed')].image",
+			expectedStrings: []string{"nginx:1.19"},
+		},
+		{
+			name:            "no comments",
+			path:            "$.spec.*[?(comment(@) == '')]",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "comments with RFC 9535",
+			path:            "$.spec[?search(comment(@), 'renovate')]",
+			options:         []yamlpath.Option{yamlpath.RFC9535},
+			expectedStrings: []string{"nginx:1.19", "envoy:1.16"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			actual, err := p.Find(&n)
+			require.NoError(t, err)
+
+			actualStrings := []string{}
+			for _, a := range actual {
+				actualStrings = append(actualStrings, a.Value)
+			}
+			require.Equal(t, tc.expectedStrings, actualStrings)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	input := `items:
+- metadata:
+    name: old
+    creationTimestamp: 2023-06-30T12:00:00Z
+  spec:
+    notAfter: 2024-03-01
+    timeout: 90s
+- metadata:
+    name: new
+    creationTimestamp: "2024-02-01T08:30:00+01:00"
+  spec:
+    notAfter: 2025-03-01
+    timeout: 5m
+- metadata:
+    name: unknown
+    creationTimestamp: yesterday
+`
+
+	cases := []struct {
+		name            string
+		path            string
+		expectedStrings []string // the values of the name of each matching node
+		focus           bool     // if true, run only tests with focus set to true
+	}{
+		{
+			name:            "timestamp less than literal",
+			path:            "$.items[?(@.metadata.creationTimestamp < '2024-01-01T00:00:00Z')]",
+			expectedStrings: []string{"old"},
+		},
+		{
+			name:            "quoted timestamp greater than or equal to literal",
+			path:            "$.items[?(@.metadata.creationTimestamp >= '2024-02-01T07:30:00Z')]",
+			expectedStrings: []string{"new"},
+		},
+		{
+			name:            "literal on the left",
+			path:            `$.items[?("2024-06-01" > @.spec.notAfter)]`,
+			expectedStrings: []string{"old"},
+		},
+		{
+			name:            "timestamp equal to literal",
+			path:            "$.items[?(@.spec.notAfter == '2025-03-01')]",
+			expectedStrings: []string{"new"},
+		},
+		{
+			name:            "timestamp not equal to literal in another form",
+			path:            "$.items[?(@.spec.notAfter == '2025-03-01T00:00:00Z')]",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "duration not equal to literal in another form",
+			path:            "$.items[?(@.spec.timeout == '300s')]",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "duration inequality compares strings",
+			path:            "$.items[?(@.spec.timeout != '1m30s')]",
+			expectedStrings: []string{"old", "new"},
+		},
+		{
+			name:            "string which is not a timestamp not ordered with respect to timestamp literal",
+			path:            "$.items[?(@.metadata.creationTimestamp >= '2000-01-01')]",
+			expectedStrings: []string{"old", "new"},
+		},
+		{
+			name:            "strings ordered with respect to string literal but timestamps not",
+			path:            "$.items[?(@.metadata.creationTimestamp > 'x')]",
+			expectedStrings: []string{"unknown"},
+		},
+		{
+			name:            "timestamps compared with each other",
+			path:            "$.items[?(@.metadata.creationTimestamp < @.spec.notAfter)]",
+			expectedStrings: []string{"old", "new"},
+		},
+		{
+			name:            "durations",
+			path:            "$.items[?(@.spec.timeout > '2m')]",
+			expectedStrings: []string{"new"},
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			var n yaml.Node
+			err := yaml.Unmarshal([]byte(input), &n)
+			require.NoError(t, err)
+
+			p, err := yamlpath.NewPath(tc.path + ".metadata.name")
+			require.NoError(t, err)
+
+			actual, err := p.Find(&n)
+			require.NoError(t, err)
+
+			actualStrings := []string{}
+			for _, a := range actual {
+				actualStrings = append(actualStrings, a.Value)
+			}
+			require.Equal(t, tc.expectedStrings, actualStrings)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
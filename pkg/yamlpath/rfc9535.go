@@ -0,0 +1,1213 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+   This file implements the syntax and semantics of RFC 9535 (https://www.rfc-editor.org/rfc/rfc9535).
+
+   Unlike the lexer, which supports the more forgiving syntax described in the README, the RFC 9535 parser is a
+   straightforward recursive descent parser over the ABNF grammar of the RFC. It produces the same kind of Path as
+   NewPath, built from matchers which follow the RFC's semantics:
+
+   * filter selectors apply to the member values of a mapping node, rather than to the mapping node itself,
+   * the descendant segment (..) visits mapping values and sequence items, but not mapping keys,
+   * comparisons follow the RFC's rules, including deep equality of mappings and sequences and the treatment of
+     empty node lists ("Nothing"),
+   * the expression is type checked when it is parsed, so that, for example, a comparison of a query which may
+     produce more than one node is rejected.
+
+   YAML values are related to JSON values as follows: mapping nodes are objects, sequence nodes are arrays, scalar
+   nodes tagged !!null, !!bool, !!int, and !!float are null, booleans, and numbers, respectively, and any other
+   scalar nodes are strings. Alias nodes are compared as the nodes they refer to.
+*/
+
+const maxSafeInteger = 1<<53 - 1 // the maximum magnitude of an integer in I-JSON (RFC 7493)
+
+// selector returns the children of a location which it selects.
+type selector func(loc *location, root *yaml.Node) locationIterator
+
+// valueFunc returns the value of a comparable in a filter, or nil if the value is Nothing.
+type valueFunc func(loc *location, root *yaml.Node) *yaml.Node
+
+// rfcSegment is a parsed child or descendant segment.
+type rfcSegment struct {
+	start      int        // position of the segment in the input
+	descendant bool       // true for a descendant segment
+	selectors  []selector // the selectors of the segment, applied in order
+	names      []string   // the names selected by the segment if it consists solely of name selectors
+	singular   bool       // true for a name segment or index segment, as defined by the RFC
+}
+
+// rfcQuery is a parsed query, either at the top level or in a filter.
+type rfcQuery struct {
+	relative bool // true if and only if the query starts with @
+	segments []rfcSegment
+	path     *Path // the segments, but not the identifier, of the query
+}
+
+func (q *rfcQuery) singular() bool {
+	for _, s := range q.segments {
+		if !s.singular {
+			return false
+		}
+	}
+	return true
+}
+
+// nodes applies the filter query to the current node, if the query is relative, or the root node.
+func (q *rfcQuery) nodes(loc *location, root *yaml.Node) []*yaml.Node {
+	return q.iterator(loc, root).nodes()
+}
+
+// first returns the first node produced by the filter query, or nil if the query produces no nodes.
+func (q *rfcQuery) first(loc *location, root *yaml.Node) *yaml.Node {
+	if l, ok := q.iterator(loc, root)(); ok {
+		return l.node
+	}
+	return nil
+}
+
+func (q *rfcQuery) iterator(loc *location, root *yaml.Node) locationIterator {
+	if !q.relative {
+		loc = loc.restart(rootValue(root))
+	}
+	return q.path.f(loc, root)
+}
+
+func rootValue(root *yaml.Node) *yaml.Node {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		return root.Content[0]
+	}
+	return root
+}
+
+// rfcParser holds the state of the RFC 9535 parser.
+type rfcParser struct {
+	input string   // the string being parsed
+	pos   int      // current position in the input
+	mark  int      // start of the current segment or filter term, used for error reports
+	opts  *options // the options with which the string is being parsed
+}
+
+func newRFC9535Path(path string, o *options) (*Path, error) {
+	q, err := parseRFC9535Query(path, o)
+	if err != nil {
+		return nil, err
+	}
+	p := rfc9535QueryPath(q)
+	p.expr = path
+	p.opts = *o
+	return p, nil
+}
+
+func parseRFC9535Query(path string, o *options) (*rfcQuery, error) {
+	p := &rfcParser{input: path, opts: o}
+	if !p.consumed(root) {
+		return nil, p.errorfExpecting([]string{root}, "query must start with %q", root)
+	}
+	q, err := p.segments(false)
+	if err != nil {
+		return nil, err
+	}
+	if !p.empty() {
+		return nil, p.errorf("invalid segment")
+	}
+	return q, nil
+}
+
+// rfc9535QueryPath converts a top-level query into a Path.
+func rfc9535QueryPath(q *rfcQuery) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind == yaml.DocumentNode {
+			loc = loc.child(0)
+		}
+		return compose(fromLocations(loc), q.path, root)
+	})
+}
+
+// rfc9535SplitLastChild is the RFC 9535 equivalent of Path.splitLastChild.
+func rfc9535SplitLastChild(path string, o *options) (*Path, []string, error) {
+	q, err := parseRFC9535Query(path, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(q.segments) == 0 {
+		return nil, nil, nil
+	}
+	last := q.segments[len(q.segments)-1]
+	if last.descendant || last.names == nil {
+		return nil, nil, nil
+	}
+	parent, err := newRFC9535Path(strings.TrimRight(path[:last.start], blankChars), o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parent, last.names, nil
+}
+
+func (p *rfcParser) empty() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *rfcParser) peek() rune {
+	if p.empty() {
+		return eof
+	}
+	r, _ := utf8.DecodeRuneInString(p.input[p.pos:])
+	return r
+}
+
+func (p *rfcParser) next() rune {
+	if p.empty() {
+		return eof
+	}
+	r, width := utf8.DecodeRuneInString(p.input[p.pos:])
+	p.pos += width
+	return r
+}
+
+func (p *rfcParser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.input[p.pos:], s)
+}
+
+func (p *rfcParser) consumed(s string) bool {
+	if p.hasPrefix(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+const blankChars = " \t\n\r"
+
+// skipBlanks consumes any blank space (the S production in the RFC).
+func (p *rfcParser) skipBlanks() {
+	for !p.empty() && strings.IndexByte(blankChars, p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// errorf returns a syntax error with context.
+func (p *rfcParser) errorf(format string, args ...interface{}) error {
+	return p.errorfExpecting(nil, format, args...)
+}
+
+// errorfExpecting is like errorf but also records the tokens which would have been valid at the current position.
+func (p *rfcParser) errorfExpecting(expected []string, format string, args ...interface{}) error {
+	mark := p.mark
+	if mark > p.pos {
+		mark = p.pos
+	}
+	msg := fmt.Sprintf("%s at position %d, following %q", fmt.Sprintf(format, args...), p.pos, p.input[mark:p.pos])
+	return newSyntaxError(msg, p.input, p.pos, p.pos, p.pos, expected)
+}
+
+// segments parses the segments of a query following the root or current node identifier.
+func (p *rfcParser) segments(relative bool) (*rfcQuery, error) {
+	q := &rfcQuery{relative: relative}
+	for {
+		// blank space may precede a segment but is otherwise left for the caller
+		pos := p.pos
+		p.skipBlanks()
+		if !p.hasPrefix(dot) && !p.hasPrefix(leftBracket) {
+			p.pos = pos
+			break
+		}
+		s, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		q.segments = append(q.segments, s)
+	}
+
+	q.path = new(identity)
+	for i := len(q.segments) - 1; i >= 0; i-- {
+		if q.segments[i].descendant {
+			q.path = descendantSegmentThen(q.segments[i].selectors, q.path)
+		} else {
+			q.path = childSegmentThen(q.segments[i].selectors, q.path)
+		}
+	}
+	return q, nil
+}
+
+func (p *rfcParser) segment() (rfcSegment, error) {
+	p.mark = p.pos
+	s := rfcSegment{start: p.pos}
+
+	switch {
+	case p.consumed(recursiveDescent):
+		s.descendant = true
+		switch {
+		case p.hasPrefix(leftBracket):
+			return p.bracketedSelection(s)
+		case p.consumed("*"):
+			s.selectors = []selector{wildcardSelector}
+			return s, nil
+		default:
+			name, ok := p.memberNameShorthand()
+			if !ok {
+				return s, p.errorf("child name, wildcard, or bracketed selection missing after descendant segment")
+			}
+			s.selectors = []selector{nameSelector(name)}
+			s.names = []string{name}
+			return s, nil
+		}
+
+	case p.consumed(dot):
+		if p.consumed("*") {
+			s.selectors = []selector{wildcardSelector}
+			return s, nil
+		}
+		name, ok := p.memberNameShorthand()
+		if !ok {
+			return s, p.errorf("child name or wildcard missing after %q", dot)
+		}
+		s.selectors = []selector{nameSelector(name)}
+		s.names = []string{name}
+		s.singular = true
+		return s, nil
+
+	default:
+		return p.bracketedSelection(s)
+	}
+}
+
+func isNameFirst(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' ||
+		r >= 0x80 && r <= 0xD7FF || r >= 0xE000 && r <= 0x10FFFF
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func (p *rfcParser) memberNameShorthand() (string, bool) {
+	start := p.pos
+	if !isNameFirst(p.peek()) {
+		return "", false
+	}
+	p.next()
+	for r := p.peek(); isNameFirst(r) || isDigit(r); r = p.peek() {
+		p.next()
+	}
+	return p.input[start:p.pos], true
+}
+
+func (p *rfcParser) bracketedSelection(s rfcSegment) (rfcSegment, error) {
+	if !p.consumed(leftBracket) {
+		return s, p.errorfExpecting([]string{leftBracket}, "missing %q", leftBracket)
+	}
+	allNames := true
+	for {
+		p.skipBlanks()
+		sel, name, singular, err := p.selector()
+		if err != nil {
+			return s, err
+		}
+		s.selectors = append(s.selectors, sel)
+		if name == nil {
+			allNames = false
+		} else {
+			s.names = append(s.names, *name)
+		}
+		s.singular = singular
+		p.skipBlanks()
+		if !p.consumed(",") {
+			break
+		}
+	}
+	if !p.consumed(rightBracket) {
+		return s, p.errorfExpecting([]string{rightBracket, ","}, `missing "]" or ","`)
+	}
+	if !allNames {
+		s.names = nil
+	}
+	s.singular = s.singular && len(s.selectors) == 1 && !s.descendant
+	return s, nil
+}
+
+// selector parses a selector and returns it together with the name it selects, if it is a name selector, and
+// whether it is a name selector or index selector.
+func (p *rfcParser) selector() (selector, *string, bool, error) {
+	switch r := p.peek(); {
+	case r == '\'' || r == '"':
+		name, err := p.stringLiteral()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return nameSelector(name), &name, true, nil
+
+	case r == '*':
+		p.next()
+		return wildcardSelector, nil, false, nil
+
+	case r == '?':
+		p.next()
+		p.skipBlanks()
+		f, err := p.logicalOr()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return filterSelector(f), nil, false, nil
+
+	case r == '-' || isDigit(r) || r == ':':
+		return p.indexOrSlice()
+
+	default:
+		return nil, nil, false, p.errorf("invalid selector")
+	}
+}
+
+// integer parses an integer within the range supported by I-JSON.
+func (p *rfcParser) integer() (int64, error) {
+	start := p.pos
+	p.consumed("-")
+	if !isDigit(p.peek()) {
+		return 0, p.errorf("invalid integer")
+	}
+	if p.consumed("0") {
+		if p.pos-start > 1 || isDigit(p.peek()) {
+			return 0, p.errorf("invalid integer")
+		}
+		return 0, nil
+	}
+	for isDigit(p.peek()) {
+		p.next()
+	}
+	i, err := strconv.ParseInt(p.input[start:p.pos], 10, 64)
+	if err != nil || i > maxSafeInteger || i < -maxSafeInteger {
+		return 0, p.errorf("integer out of range")
+	}
+	return i, nil
+}
+
+func (p *rfcParser) indexOrSlice() (selector, *string, bool, error) {
+	var bounds [3]*int64
+	if p.peek() != ':' {
+		i, err := p.integer()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		bounds[0] = &i
+		p.skipBlanks()
+		if p.peek() != ':' {
+			return indexSelector(i), nil, true, nil
+		}
+	}
+
+	// slice
+	for b := 1; b < 3; b++ {
+		if !p.consumed(":") {
+			break
+		}
+		p.skipBlanks()
+		if r := p.peek(); r == '-' || isDigit(r) {
+			i, err := p.integer()
+			if err != nil {
+				return nil, nil, false, err
+			}
+			bounds[b] = &i
+			p.skipBlanks()
+		}
+	}
+	return sliceSelector(bounds[0], bounds[1], bounds[2]), nil, false, nil
+}
+
+// stringLiteral parses a single- or double-quoted string literal and returns its unescaped value.
+func (p *rfcParser) stringLiteral() (string, error) {
+	quote := p.next()
+	var b strings.Builder
+	for {
+		r := p.next()
+		switch {
+		case r == eof:
+			return "", p.errorfExpecting([]string{string(quote)}, "unmatched string delimiter %q", quote)
+
+		case r == quote:
+			return b.String(), nil
+
+		case r < 0x20:
+			return "", p.errorf("invalid control character %U in string literal", r)
+
+		case r == '\\':
+			e := p.next()
+			switch e {
+			case 'b':
+				b.WriteRune('\b')
+			case 'f':
+				b.WriteRune('\f')
+			case 'n':
+				b.WriteRune('\n')
+			case 'r':
+				b.WriteRune('\r')
+			case 't':
+				b.WriteRune('\t')
+			case '/', '\\', quote:
+				b.WriteRune(e)
+			case 'u':
+				u, err := p.unicodeEscape()
+				if err != nil {
+					return "", err
+				}
+				b.WriteRune(u)
+			default:
+				return "", p.errorf("invalid escape sequence in string literal")
+			}
+
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// unicodeEscape parses the hexadecimal digits of a \u escape sequence, including a following low surrogate if the
+// escaped character is a high surrogate.
+func (p *rfcParser) unicodeEscape() (rune, error) {
+	hex := func() (rune, error) {
+		if p.pos+4 > len(p.input) {
+			return 0, p.errorf("invalid unicode escape sequence")
+		}
+		u, err := strconv.ParseUint(p.input[p.pos:p.pos+4], 16, 32)
+		if err != nil {
+			return 0, p.errorf("invalid unicode escape sequence")
+		}
+		p.pos += 4
+		return rune(u), nil
+	}
+	u, err := hex()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case u >= 0xDC00 && u <= 0xDFFF:
+		return 0, p.errorf("invalid unicode escape sequence: unpaired low surrogate")
+
+	case u >= 0xD800 && u <= 0xDBFF:
+		if !p.consumed(`\u`) {
+			return 0, p.errorf("invalid unicode escape sequence: unpaired high surrogate")
+		}
+		low, err := hex()
+		if err != nil {
+			return 0, err
+		}
+		if low < 0xDC00 || low > 0xDFFF {
+			return 0, p.errorf("invalid unicode escape sequence: unpaired high surrogate")
+		}
+		return (u-0xD800)<<10 + (low - 0xDC00) + 0x10000, nil
+	}
+	return u, nil
+}
+
+// logicalOr parses a logical-or-expr.
+func (p *rfcParser) logicalOr() (filter, error) {
+	f, err := p.logicalAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pos := p.pos
+		p.skipBlanks()
+		if !p.consumed(filterDisjunction) {
+			p.pos = pos
+			return f, nil
+		}
+		p.skipBlanks()
+		g, err := p.logicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		f1 := f
+		f = func(loc *location, root *yaml.Node) bool {
+			return f1(loc, root) || g(loc, root)
+		}
+	}
+}
+
+// logicalAnd parses a logical-and-expr.
+func (p *rfcParser) logicalAnd() (filter, error) {
+	f, err := p.basicExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pos := p.pos
+		p.skipBlanks()
+		if !p.consumed(filterConjunction) {
+			p.pos = pos
+			return f, nil
+		}
+		p.skipBlanks()
+		g, err := p.basicExpr()
+		if err != nil {
+			return nil, err
+		}
+		f1 := f
+		f = func(loc *location, root *yaml.Node) bool {
+			return f1(loc, root) && g(loc, root)
+		}
+	}
+}
+
+// basicExpr parses a paren-expr, comparison-expr, or test-expr.
+func (p *rfcParser) basicExpr() (filter, error) {
+	p.mark = p.pos
+	if p.consumed(filterNot) {
+		p.skipBlanks()
+		var f filter
+		var err error
+		if p.peek() == '(' {
+			f, err = p.parenExpr()
+		} else {
+			f, err = p.testExpr()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(loc *location, root *yaml.Node) bool {
+			return !f(loc, root)
+		}, nil
+	}
+
+	if p.peek() == '(' {
+		return p.parenExpr()
+	}
+
+	if p.peekedFunction() {
+		fe, err := p.functionExpr()
+		if err != nil {
+			return nil, err
+		}
+		op, ok := p.comparisonOperator()
+		if !ok {
+			return p.functionTest(fe)
+		}
+		v, err := p.functionComparable(fe)
+		if err != nil {
+			return nil, err
+		}
+		return p.comparison(v, op)
+	}
+
+	switch r := p.peek(); r {
+	case '@', '$':
+		q, err := p.filterQuery()
+		if err != nil {
+			return nil, err
+		}
+		op, ok := p.comparisonOperator()
+		if !ok {
+			return existenceFilter(q), nil
+		}
+		if !q.singular() {
+			return nil, p.errorf("non-singular query cannot be compared")
+		}
+		return p.comparison(singularQueryValue(q), op)
+
+	default:
+		lhs, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		op, ok := p.comparisonOperator()
+		if !ok {
+			return nil, p.errorf("literal must be compared")
+		}
+		return p.comparison(lhs, op)
+	}
+}
+
+func (p *rfcParser) parenExpr() (filter, error) {
+	p.consumed(filterOpenBracket)
+	p.skipBlanks()
+	f, err := p.logicalOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlanks()
+	if !p.consumed(filterCloseBracket) {
+		return nil, p.errorfExpecting([]string{filterCloseBracket}, "missing %q", filterCloseBracket)
+	}
+	return f, nil
+}
+
+// testExpr parses a test-expr following a logical not operator.
+func (p *rfcParser) testExpr() (filter, error) {
+	switch {
+	case p.peek() == '@' || p.peek() == '$':
+		q, err := p.filterQuery()
+		if err != nil {
+			return nil, err
+		}
+		return existenceFilter(q), nil
+
+	case p.peekedFunction():
+		fe, err := p.functionExpr()
+		if err != nil {
+			return nil, err
+		}
+		return p.functionTest(fe)
+
+	default:
+		return nil, p.errorf("invalid test expression")
+	}
+}
+
+// rfcFunctionExpr is a parsed function expression.
+type rfcFunctionExpr struct {
+	name string
+	fn   *function
+	call functionCallFunc // evaluates the arguments and calls the function
+}
+
+// functionTest converts a function expression to a filter, provided the function's result is of type LogicalType or
+// NodesType.
+func (p *rfcParser) functionTest(fe *rfcFunctionExpr) (filter, error) {
+	switch fe.fn.result {
+	case LogicalType:
+		return func(loc *location, root *yaml.Node) bool {
+			return fe.call(loc, root).Logical
+		}, nil
+
+	case NodesType:
+		return func(loc *location, root *yaml.Node) bool {
+			return len(fe.call(loc, root).Nodes) > 0
+		}, nil
+
+	default:
+		return nil, p.errorf("result of function %s() must be compared", fe.name)
+	}
+}
+
+// functionComparable converts a function expression to a comparable, provided the function's result is of type
+// ValueType.
+func (p *rfcParser) functionComparable(fe *rfcFunctionExpr) (valueFunc, error) {
+	if fe.fn.result != ValueType {
+		return nil, p.errorf("result of function %s() is of type %s and cannot be compared", fe.name, fe.fn.result)
+	}
+	return func(loc *location, root *yaml.Node) *yaml.Node {
+		return fe.call(loc, root).Value
+	}, nil
+}
+
+// peekedFunction returns true if and only if the input continues with a function name followed by an opening bracket.
+func (p *rfcParser) peekedFunction() bool {
+	pos := p.pos
+	defer func() {
+		p.pos = pos
+	}()
+	if r := p.peek(); r < 'a' || r > 'z' {
+		return false
+	}
+	for isFunctionNameChar(p.peek()) {
+		p.next()
+	}
+	return p.hasPrefix(filterOpenBracket)
+}
+
+func isFunctionNameChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r == '_' || isDigit(r)
+}
+
+// functionExpr parses a function-expr.
+func (p *rfcParser) functionExpr() (*rfcFunctionExpr, error) {
+	start := p.pos
+	for isFunctionNameChar(p.peek()) {
+		p.next()
+	}
+	name := p.input[start:p.pos]
+	p.consumed(filterOpenBracket)
+	f, ok := p.opts.function(name)
+	if !ok {
+		return nil, p.errorf("unknown function %s()", name)
+	}
+
+	args := []functionCallFunc{}
+	literals := []*yaml.Node{}
+	for i, t := range f.params {
+		p.skipBlanks()
+		if i > 0 && !p.consumed(filterArgumentSeparator) {
+			return nil, p.errorf("function %s() takes %d argument(s)", name, len(f.params))
+		}
+		p.skipBlanks()
+		arg, literal, err := p.functionArgument(t)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		literals = append(literals, literal)
+	}
+	p.skipBlanks()
+	if !p.consumed(filterCloseBracket) {
+		if p.hasPrefix(filterArgumentSeparator) {
+			return nil, p.errorf("function %s() takes %d argument(s)", name, len(f.params))
+		}
+		return nil, p.errorfExpecting([]string{filterCloseBracket}, "missing %q", filterCloseBracket)
+	}
+
+	call := f.implementation(literals)
+	return &rfcFunctionExpr{
+		name: name,
+		fn:   f,
+		call: func(loc *location, root *yaml.Node) FunctionValue {
+			vals := []FunctionValue{}
+			for _, a := range args {
+				vals = append(vals, a(loc, root))
+			}
+			return call(loc.eval, vals)
+		},
+	}, nil
+}
+
+// functionArgument parses an argument of a function extension with the given parameter type. It also returns the
+// value of the argument if it is a literal, otherwise nil.
+func (p *rfcParser) functionArgument(t FunctionType) (functionCallFunc, *yaml.Node, error) {
+	if t == LogicalType {
+		f, err := p.logicalOr()
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			return FunctionValue{Logical: f(loc, root)}
+		}, nil, nil
+	}
+
+	switch {
+	case p.peekedFunction():
+		fe, err := p.functionExpr()
+		if err != nil {
+			return nil, nil, err
+		}
+		if fe.fn.result != t {
+			return nil, nil, p.errorf("result of function %s() is of type %s but %s is required", fe.name, fe.fn.result, t)
+		}
+		return fe.call, nil, nil
+
+	case p.peek() == '@' || p.peek() == '$':
+		q, err := p.filterQuery()
+		if err != nil {
+			return nil, nil, err
+		}
+		if t == NodesType {
+			return func(loc *location, root *yaml.Node) FunctionValue {
+				return FunctionValue{Nodes: q.nodes(loc, root)}
+			}, nil, nil
+		}
+		if !q.singular() {
+			return nil, nil, p.errorf("query must be singular")
+		}
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			if l, ok := q.iterator(loc, root)(); ok {
+				return l.valueArgument()
+			}
+			return FunctionValue{}
+		}, nil, nil
+
+	case t == ValueType:
+		n, err := p.literalNode()
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(loc *location, root *yaml.Node) FunctionValue {
+			return FunctionValue{Value: n}
+		}, n, nil
+
+	default:
+		return nil, nil, p.errorf("%s is required", t)
+	}
+}
+
+func (p *rfcParser) filterQuery() (*rfcQuery, error) {
+	relative := p.next() == '@'
+	return p.segments(relative)
+}
+
+// comparisonOperator consumes a comparison operator, if present, together with any surrounding blank space.
+func (p *rfcParser) comparisonOperator() (string, bool) {
+	pos := p.pos
+	p.skipBlanks()
+	for _, op := range []string{filterEquality, filterInequality, operatorLessThanOrEqual.String(), operatorLessThan.String(),
+		operatorGreaterThanOrEqual.String(), operatorGreaterThan.String()} {
+		if p.consumed(op) {
+			p.skipBlanks()
+			return op, true
+		}
+	}
+	p.pos = pos
+	return "", false
+}
+
+// comparison parses the right hand side of a comparison.
+func (p *rfcParser) comparison(lhs valueFunc, op string) (filter, error) {
+	rhs, err := p.comparable()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.comparisonOperator(); ok {
+		return nil, p.errorf("comparisons cannot be chained")
+	}
+	return comparisonOf(op, lhs, rhs), nil
+}
+
+// comparable parses a literal, singular query, or function expression.
+func (p *rfcParser) comparable() (valueFunc, error) {
+	if p.peekedFunction() {
+		fe, err := p.functionExpr()
+		if err != nil {
+			return nil, err
+		}
+		return p.functionComparable(fe)
+	}
+
+	switch p.peek() {
+	case '@', '$':
+		q, err := p.filterQuery()
+		if err != nil {
+			return nil, err
+		}
+		if !q.singular() {
+			return nil, p.errorf("non-singular query cannot be compared")
+		}
+		return singularQueryValue(q), nil
+
+	default:
+		return p.literal()
+	}
+}
+
+// literal parses a string, number, boolean, or null literal.
+func (p *rfcParser) literal() (valueFunc, error) {
+	n, err := p.literalNode()
+	if err != nil {
+		return nil, err
+	}
+	return func(loc *location, root *yaml.Node) *yaml.Node {
+		return n
+	}, nil
+}
+
+// literalNode parses a literal and returns its value.
+func (p *rfcParser) literalNode() (*yaml.Node, error) {
+	start := p.pos
+	var n *yaml.Node
+	switch r := p.peek(); {
+	case r == '\'' || r == '"':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		n = &yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: s}
+
+	case r == '-' || isDigit(r):
+		var err error
+		n, err = p.numberLiteral()
+		if err != nil {
+			return nil, err
+		}
+
+	case p.consumed("true"), p.consumed("false"):
+		n = &yaml.Node{Kind: yaml.ScalarNode, Tag: boolTag, Value: p.input[start:p.pos]}
+
+	case p.consumed("null"):
+		n = &yaml.Node{Kind: yaml.ScalarNode, Tag: nullTag, Value: "null"}
+
+	default:
+		return nil, p.errorf("invalid filter term")
+	}
+	return n, nil
+}
+
+func (p *rfcParser) numberLiteral() (*yaml.Node, error) {
+	start := p.pos
+	tag := intTag
+	if p.consumed("-0") {
+		if isDigit(p.peek()) {
+			return nil, p.errorf("invalid number")
+		}
+	} else if _, err := p.integer(); err != nil {
+		return nil, p.errorf("invalid number")
+	}
+	if p.consumed(".") {
+		tag = floatTag
+		if !isDigit(p.peek()) {
+			return nil, p.errorf("invalid number")
+		}
+		for isDigit(p.peek()) {
+			p.next()
+		}
+	}
+	if p.consumed("e") || p.consumed("E") {
+		tag = floatTag
+		if !p.consumed("-") {
+			p.consumed("+")
+		}
+		if !isDigit(p.peek()) {
+			return nil, p.errorf("invalid number")
+		}
+		for isDigit(p.peek()) {
+			p.next()
+		}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: p.input[start:p.pos]}, nil
+}
+
+func existenceFilter(q *rfcQuery) filter {
+	return func(loc *location, root *yaml.Node) bool {
+		return q.first(loc, root) != nil
+	}
+}
+
+func singularQueryValue(q *rfcQuery) valueFunc {
+	return func(loc *location, root *yaml.Node) *yaml.Node {
+		return q.first(loc, root)
+	}
+}
+
+func comparisonOf(op string, lhs, rhs valueFunc) filter {
+	return func(loc *location, root *yaml.Node) bool {
+		l, r := lhs(loc, root), rhs(loc, root)
+		switch op {
+		case filterEquality:
+			return rfcSame(l, r)
+		case filterInequality:
+			return !rfcSame(l, r)
+		case operatorLessThan.String():
+			return rfcLess(l, r)
+		case operatorLessThanOrEqual.String():
+			return rfcLess(l, r) || rfcSame(l, r)
+		case operatorGreaterThan.String():
+			return rfcLess(r, l)
+		default: // operatorGreaterThanOrEqual
+			return rfcLess(r, l) || rfcSame(l, r)
+		}
+	}
+}
+
+func childSegmentThen(selectors []selector, p *Path) *Path {
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		its := []locationIterator{}
+		for _, s := range selectors {
+			its = append(its, compose(s(loc, root), p, root))
+		}
+		return fromLocationIterators(its...)
+	})
+}
+
+func descendantSegmentThen(selectors []selector, p *Path) *Path {
+	children := childSegmentThen(selectors, p)
+	return new(func(loc *location, root *yaml.Node) locationIterator {
+		return compose(fromLocations(loc).descendants(), children, root)
+	})
+}
+
+func nameSelector(name string) selector {
+	return func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind == yaml.MappingNode {
+			if loc.mergeKeys {
+				if e := loc.namedEntries([]string{name}); len(e) > 0 {
+					return fromLocations(e[0].value())
+				}
+				return fromLocations()
+			}
+			if i := keyIndex(loc.node, name); i >= 0 {
+				return fromLocations(loc.child(i + 1))
+			}
+		}
+		return fromLocations()
+	}
+}
+
+func wildcardSelector(loc *location, root *yaml.Node) locationIterator {
+	return fromLocations(loc).values()
+}
+
+func indexSelector(index int64) selector {
+	return func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind != yaml.SequenceNode {
+			return fromLocations()
+		}
+		i := index
+		if i < 0 {
+			i += int64(len(loc.node.Content))
+		}
+		if i < 0 || i >= int64(len(loc.node.Content)) {
+			return fromLocations()
+		}
+		return fromLocations(loc.child(int(i)))
+	}
+}
+
+func sliceSelector(start, end, step *int64) selector {
+	return func(loc *location, root *yaml.Node) locationIterator {
+		if loc.node.Kind != yaml.SequenceNode {
+			return fromLocations()
+		}
+		return fromLocations(loc).indices(func(length int) indexIterator {
+			return normalizedSlice(start, end, step, length)
+		})
+	}
+}
+
+func filterSelector(f filter) selector {
+	return func(loc *location, root *yaml.Node) locationIterator {
+		return fromLocations(loc).values().filter(f, root)
+	}
+}
+
+// jsonType is the type of the JSON value corresponding to a YAML node.
+type jsonType int
+
+const (
+	jsonNull jsonType = iota
+	jsonBoolean
+	jsonNumber
+	jsonString
+	jsonArray
+	jsonObject
+)
+
+func dealias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+func jsonTypeOf(n *yaml.Node) jsonType {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return jsonObject
+
+	case yaml.SequenceNode:
+		return jsonArray
+	}
+	switch n.ShortTag() {
+	case nullTag:
+		return jsonNull
+
+	case boolTag:
+		return jsonBoolean
+
+	case intTag, floatTag:
+		return jsonNumber
+
+	default:
+		return jsonString
+	}
+}
+
+// numberValue returns the value of a number, or false if the node is explicitly tagged as a number, such as
+// `!!int foo`, but its value is not a number.
+func numberValue(n *yaml.Node) (float64, bool) {
+	var f float64
+	if err := n.Decode(&f); err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// booleanValue returns the value of a boolean, or false if the node is explicitly tagged as a boolean, such as
+// `!!bool yes`, but its value is not a boolean.
+func booleanValue(n *yaml.Node) (bool, bool) {
+	var b bool
+	if err := n.Decode(&b); err != nil {
+		return false, false
+	}
+	return b, true
+}
+
+// rfcSame compares two values, either of which may be Nothing (nil), for equality.
+func rfcSame(l, r *yaml.Node) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	return rfcEqual(l, r)
+}
+
+// rfcEqual compares two values for equality.
+func rfcEqual(l, r *yaml.Node) bool {
+	l, r = dealias(l), dealias(r)
+	t := jsonTypeOf(l)
+	if t != jsonTypeOf(r) {
+		return false
+	}
+	switch t {
+	case jsonNull:
+		return true
+
+	case jsonBoolean:
+		// invalid booleans are incomparable
+		lb, lok := booleanValue(l)
+		rb, rok := booleanValue(r)
+		return lok && rok && lb == rb
+
+	case jsonNumber:
+		// invalid numbers are incomparable
+		lf, lok := numberValue(l)
+		rf, rok := numberValue(r)
+		return lok && rok && lf == rf
+
+	case jsonString:
+		return l.Value == r.Value
+
+	case jsonArray:
+		if len(l.Content) != len(r.Content) {
+			return false
+		}
+		for i := range l.Content {
+			if !rfcEqual(l.Content[i], r.Content[i]) {
+				return false
+			}
+		}
+		return true
+
+	default: // jsonObject
+		if len(l.Content) != len(r.Content) {
+			return false
+		}
+		for i := 0; i+1 < len(l.Content); i += 2 {
+			j := keyIndex(r, l.Content[i].Value)
+			if j < 0 || !rfcEqual(l.Content[i+1], r.Content[j+1]) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// rfcLess returns true if and only if the first value is less than the second. Only numbers and strings are ordered.
+func rfcLess(l, r *yaml.Node) bool {
+	if l == nil || r == nil {
+		return false
+	}
+	l, r = dealias(l), dealias(r)
+	t := jsonTypeOf(l)
+	if t != jsonTypeOf(r) {
+		return false
+	}
+	switch t {
+	case jsonNumber:
+		lf, lok := numberValue(l)
+		rf, rok := numberValue(r)
+		return lok && rok && lf < rf
+
+	case jsonString:
+		return l.Value < r.Value // comparing UTF-8 byte sequences is equivalent to comparing code points
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,552 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRFC9535(t *testing.T) {
+	// examples from RFC 9535
+	store := `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+	cases := []struct {
+		name            string
+		input           string
+		path            string
+		expectedStrings []string
+		expectedPaths   []string // normalized paths of the results, if not nil
+		expectedPathErr string
+		focus           bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:            "authors of all books",
+			input:           store,
+			path:            "$.store.book[*].author",
+			expectedStrings: []string{"\"Nigel Rees\"\n", "\"Evelyn Waugh\"\n", "\"Herman Melville\"\n", "\"J. R. R. Tolkien\"\n"},
+		},
+		{
+			name:            "all authors",
+			input:           store,
+			path:            "$..author",
+			expectedStrings: []string{"\"Nigel Rees\"\n", "\"Evelyn Waugh\"\n", "\"Herman Melville\"\n", "\"J. R. R. Tolkien\"\n"},
+		},
+		{
+			name:            "prices of everything",
+			input:           store,
+			path:            "$.store..price",
+			expectedStrings: []string{"8.95\n", "12.99\n", "8.99\n", "22.99\n", "399\n"},
+			expectedPaths: []string{
+				"$['store']['book'][0]['price']",
+				"$['store']['book'][1]['price']",
+				"$['store']['book'][2]['price']",
+				"$['store']['book'][3]['price']",
+				"$['store']['bicycle']['price']",
+			},
+		},
+		{
+			name:            "last book",
+			input:           store,
+			path:            "$..book[-1].title",
+			expectedStrings: []string{"\"The Lord of the Rings\"\n"},
+		},
+		{
+			name:            "first two books",
+			input:           store,
+			path:            "$..book[0,1].title",
+			expectedStrings: []string{"\"Sayings of the Century\"\n", "\"Sword of Honour\"\n"},
+		},
+		{
+			name:            "books with isbn",
+			input:           store,
+			path:            "$..book[?@.isbn].title",
+			expectedStrings: []string{"\"Moby Dick\"\n", "\"The Lord of the Rings\"\n"},
+		},
+		{
+			name:            "cheap books",
+			input:           store,
+			path:            "$..book[?@.price<10].title",
+			expectedStrings: []string{"\"Sayings of the Century\"\n", "\"Moby Dick\"\n"},
+		},
+		{
+			name:            "blank space",
+			input:           store,
+			path:            "$ .store [ 'book' ] [ ? @.price < 10 && ( @.category == \"fiction\" ) ] .title",
+			expectedStrings: []string{"\"Moby Dick\"\n"},
+		},
+		{
+			name:            "filter applies to mapping values",
+			input:           `{"a": {"x": 1}, "b": {"x": 2}, "c": 3}`,
+			path:            "$[?@.x > 1]",
+			expectedStrings: []string{"{\"x\": 2}\n"},
+			expectedPaths:   []string{"$['b']"},
+		},
+		{
+			name:            "filter on mapping matching key",
+			input:           `{"id": 2}`,
+			path:            "$[?(@.id==2)]",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "comparison of absent values",
+			input:           `[{"a": 1}, {"b": 1}]`,
+			path:            "$[?@.x == @.y]",
+			expectedStrings: []string{"{\"a\": 1}\n", "{\"b\": 1}\n"},
+		},
+		{
+			name:            "comparison of absent value with literal",
+			input:           `[{"a": 1}, {"b": 1}]`,
+			path:            "$[?@.a != 1]",
+			expectedStrings: []string{"{\"b\": 1}\n"},
+		},
+		{
+			name:            "less than or equal with absent values",
+			input:           `[{"a": 1}]`,
+			path:            "$[?@.x <= @.y]",
+			expectedStrings: []string{"{\"a\": 1}\n"},
+		},
+		{
+			name:            "deep equality",
+			input:           `[{"a": {"b": [1, {"c": 2}]}}, {"a": {"b": [1, {"c": 3}]}}]`,
+			path:            "$[?@.a == $[0].a]",
+			expectedStrings: []string{"{\"a\": {\"b\": [1, {\"c\": 2}]}}\n"},
+		},
+		{
+			name:            "numbers compare numerically",
+			input:           `[1, 1.0, 1e0, "1", 0x1, 2]`,
+			path:            "$[?@ == 1]",
+			expectedStrings: []string{"1\n", "1.0\n", "1e0\n", "0x1\n"},
+		},
+		{
+			name:            "strings are ordered",
+			input:           `["a", "b", "B", "ab", 1]`,
+			path:            "$[?@ < 'b']",
+			expectedStrings: []string{"\"a\"\n", "\"B\"\n", "\"ab\"\n"},
+		},
+		{
+			name:            "different types are not equal",
+			input:           `[true, "true", null, "null", 0, false]`,
+			path:            "$[?@ == true || @ == null]",
+			expectedStrings: []string{"true\n", "null\n"},
+		},
+		{
+			name:            "negated parenthesised expression",
+			input:           `[1, 2, 3]`,
+			path:            "$[?!(@ > 1 && @ < 3)]",
+			expectedStrings: []string{"1\n", "3\n"},
+		},
+		{
+			name:            "negated existence",
+			input:           `[{"a": 1}, {"b": 1}]`,
+			path:            "$[?!@.a]",
+			expectedStrings: []string{"{\"b\": 1}\n"},
+		},
+		{
+			name:            "root in filter",
+			input:           `{"limit": 2, "values": [1, 2, 3]}`,
+			path:            "$.values[?@ >= $.limit]",
+			expectedStrings: []string{"2\n", "3\n"},
+		},
+		{
+			name:            "nested filter",
+			input:           `[{"a": [1, 2]}, {"a": [3]}]`,
+			path:            "$[?@.a[?@ > 2]]",
+			expectedStrings: []string{"{\"a\": [3]}\n"},
+		},
+		{
+			name:            "descendant segment excludes keys",
+			input:           `{"a": {"a": 1}}`,
+			path:            "$..[?@ == 'a']",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "descendant wildcard",
+			input:           `{"a": [1, {"b": 2}]}`,
+			path:            "$..*",
+			expectedStrings: []string{"[1, {\"b\": 2}]\n", "1\n", "{\"b\": 2}\n", "2\n"},
+		},
+		{
+			name:            "duplicates in union",
+			input:           `[0, 1]`,
+			path:            "$[0, 0, -1]",
+			expectedStrings: []string{"0\n", "0\n", "1\n"},
+		},
+		{
+			name:            "slice with negative step",
+			input:           `[0, 1, 2, 3, 4]`,
+			path:            "$[::-2]",
+			expectedStrings: []string{"4\n", "2\n", "0\n"},
+		},
+		{
+			name:            "slice with zero step",
+			input:           `[0, 1, 2]`,
+			path:            "$[::0]",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "slice with large bounds",
+			input:           `[0, 1, 2]`,
+			path:            "$[2:-113667776004:-1]",
+			expectedStrings: []string{"2\n", "1\n", "0\n"},
+		},
+		{
+			name:            "escaped name",
+			input:           "{\"a'\\u263a\": 1}",
+			path:            `$['a\'\u263A']`,
+			expectedStrings: []string{"1\n"},
+			expectedPaths:   []string{"$['a\\'☺']"},
+		},
+		{
+			name:            "surrogate pair",
+			input:           "{\"\U0001F600\": 1}",
+			path:            `$["\uD83D\uDE00"]`,
+			expectedStrings: []string{"1\n"},
+		},
+		{
+			name:            "non-ASCII shorthand",
+			input:           "{\"☺\": 1}",
+			path:            `$.☺`,
+			expectedStrings: []string{"1\n"},
+		},
+		{
+			name:            "root",
+			input:           `{"a": 1}`,
+			path:            "$",
+			expectedStrings: []string{"{\"a\": 1}\n"},
+			expectedPaths:   []string{"$"},
+		},
+		{
+			name:            "length function",
+			input:           store,
+			path:            "$.store.book[?length(@.title) > 15].title",
+			expectedStrings: []string{"\"Sayings of the Century\"\n", "\"The Lord of the Rings\"\n"},
+		},
+		{
+			name:            "length of non-string, non-container",
+			input:           `[{"a": 1}, {"a": [1, 2]}, {"a": {"b": 1}}, {"a": "☺"}]`,
+			path:            "$[?length(@.a) == 1]",
+			expectedStrings: []string{"{\"a\": {\"b\": 1}}\n", "{\"a\": \"☺\"}\n"},
+		},
+		{
+			name:            "count function",
+			input:           store,
+			path:            "$.store.book[?count(@.*) == 5].title",
+			expectedStrings: []string{"\"Moby Dick\"\n", "\"The Lord of the Rings\"\n"},
+		},
+		{
+			name:            "match function",
+			input:           store,
+			path:            "$.store.book[?match(@.author, 'J.*')].author",
+			expectedStrings: []string{"\"J. R. R. Tolkien\"\n"},
+		},
+		{
+			name:            "match function matches entire string",
+			input:           store,
+			path:            "$.store.book[?match(@.author, 'Tolkien')].author",
+			expectedStrings: []string{},
+		},
+		{
+			name:            "search function",
+			input:           store,
+			path:            "$.store.book[?search(@.author, 'Tolkien')].author",
+			expectedStrings: []string{"\"J. R. R. Tolkien\"\n"},
+		},
+		{
+			name:            "dot in regular expression does not match carriage return",
+			input:           `["a\r", "ab"]`,
+			path:            "$[?match(@, 'a.')]",
+			expectedStrings: []string{"\"ab\"\n"},
+		},
+		{
+			name:            "negated match function",
+			input:           `["ab", "cd"]`,
+			path:            "$[?!match(@, 'a.')]",
+			expectedStrings: []string{"\"cd\"\n"},
+		},
+		{
+			name:            "value function",
+			input:           `[{"a": {"b": 1}}, {"a": {"b": 1, "c": {"b": 1}}}]`,
+			path:            "$[?value(@..b) == 1]",
+			expectedStrings: []string{"{\"a\": {\"b\": 1}}\n"},
+		},
+		{
+			name:            "nested functions",
+			input:           `[[1, 2], [3]]`,
+			path:            "$[?length(value(@)) == 1]",
+			expectedStrings: []string{"[3]\n"},
+		},
+		{
+			name:            "function result not compared",
+			path:            "$[?length(@)]",
+			expectedPathErr: `result of function length() must be compared at position 12, following "length(@)"`,
+		},
+		{
+			name:            "logical function result compared",
+			path:            "$[?match(@, 'a') == true]",
+			expectedPathErr: `result of function match() is of type LogicalType and cannot be compared at position 20, following "match(@, 'a') == "`,
+		},
+		{
+			name:            "non-singular query as value argument",
+			path:            "$[?length(@.*) == 1]",
+			expectedPathErr: `query must be singular at position 13, following ".*"`,
+		},
+		{
+			name:            "literal as nodes argument",
+			path:            "$[?count(1) == 1]",
+			expectedPathErr: `NodesType is required at position 9, following "count("`,
+		},
+		{
+			name:            "value function result as nodes argument",
+			path:            "$[?count(value(@)) == 1]",
+			expectedPathErr: `result of function value() is of type ValueType but NodesType is required at position 17, following "count(value(@)"`,
+		},
+		{
+			name:            "too many arguments",
+			path:            "$[?length(@, @) == 1]",
+			expectedPathErr: `function length() takes 1 argument(s) at position 11, following "length(@"`,
+		},
+		{
+			name:            "unknown function",
+			path:            "$[?foo(@)]",
+			expectedPathErr: `unknown function foo() at position 7, following "foo("`,
+		},
+		{
+			name:            "missing root",
+			path:            "a",
+			expectedPathErr: `query must start with "$" at position 0, following ""`,
+		},
+		{
+			name:            "empty path",
+			path:            "",
+			expectedPathErr: `query must start with "$" at position 0, following ""`,
+		},
+		{
+			name:            "trailing blank space",
+			path:            "$.a ",
+			expectedPathErr: `invalid segment at position 3, following ".a"`,
+		},
+		{
+			name:            "undotted child",
+			path:            "$a",
+			expectedPathErr: `invalid segment at position 1, following "$"`,
+		},
+		{
+			name:            "blank space after dot",
+			path:            "$. a",
+			expectedPathErr: `child name or wildcard missing after "." at position 2, following "."`,
+		},
+		{
+			name:            "shorthand starting with digit",
+			path:            "$.1a",
+			expectedPathErr: `child name or wildcard missing after "." at position 2, following "."`,
+		},
+		{
+			name:            "leading zero",
+			path:            "$[01]",
+			expectedPathErr: `invalid integer at position 3, following "[0"`,
+		},
+		{
+			name:            "negative zero index",
+			path:            "$[-0]",
+			expectedPathErr: `invalid integer at position 4, following "[-0"`,
+		},
+		{
+			name:            "index out of range",
+			path:            "$[9007199254740992]",
+			expectedPathErr: `integer out of range at position 18, following "[9007199254740992"`,
+		},
+		{
+			name:            "invalid escape",
+			path:            `$['\a']`,
+			expectedPathErr: `invalid escape sequence in string literal at position 5, following "['\\a"`,
+		},
+		{
+			name:            "escaped double quote in single quoted string",
+			path:            `$['\"']`,
+			expectedPathErr: `invalid escape sequence in string literal at position 5, following "['\\\""`,
+		},
+		{
+			name:            "unpaired surrogate",
+			path:            `$['\uD83D']`,
+			expectedPathErr: `invalid unicode escape sequence: unpaired high surrogate at position 9, following "['\\uD83D"`,
+		},
+		{
+			name:            "control character in string",
+			path:            "$['\t']",
+			expectedPathErr: `invalid control character U+0009 in string literal at position 4, following "['\t"`,
+		},
+		{
+			name:            "comparison of non-singular query",
+			path:            "$[?@.* == 1]",
+			expectedPathErr: `non-singular query cannot be compared at position 10, following ".* == "`,
+		},
+		{
+			name:            "comparison of non-singular query on right",
+			path:            "$[?1 == @..a]",
+			expectedPathErr: `non-singular query cannot be compared at position 12, following "..a"`,
+		},
+		{
+			name:            "literal without comparison",
+			path:            "$[?true]",
+			expectedPathErr: `literal must be compared at position 7, following "true"`,
+		},
+		{
+			name:            "negated comparison without brackets",
+			path:            "$[?!@.a == 1]",
+			expectedPathErr: `missing "]" or "," at position 8, following ".a "`,
+		},
+		{
+			name:            "chained comparison",
+			path:            "$[?1 < @ < 3]",
+			expectedPathErr: `comparisons cannot be chained at position 11, following "1 < @ < "`,
+		},
+		{
+			name:            "regular expression match is not supported",
+			path:            "$[?@ =~ /a/]",
+			expectedPathErr: `missing "]" or "," at position 5, following "@ "`,
+		},
+		{
+			name:            "property name operator is not supported",
+			path:            "$.a~",
+			expectedPathErr: `invalid segment at position 3, following ".a"`,
+		},
+		{
+			name:            "single equals",
+			path:            "$[?@.a = 1]",
+			expectedPathErr: `missing "]" or "," at position 7, following ".a "`,
+		},
+		{
+			name:            "number with leading plus",
+			path:            "$[?@ == +1]",
+			expectedPathErr: `invalid filter term at position 8, following "@ == "`,
+		},
+		{
+			name:            "tagged scalars which are not numbers are not equal to numbers",
+			input:           "[!!int foo, !!float x, 1]",
+			path:            "$[?@ == 1]",
+			expectedStrings: []string{"1\n"},
+		},
+		{
+			name:            "tagged scalars which are not numbers are unequal to numbers",
+			input:           "[!!int foo, !!float x, 1]",
+			path:            "$[?@ != 1]",
+			expectedStrings: []string{"!!int foo\n", "!!float x\n"},
+		},
+		{
+			name:            "tagged scalars which are not numbers are not ordered",
+			input:           "[!!int foo, !!float x, 1]",
+			path:            "$[?@ < 2 || @ >= 2]",
+			expectedStrings: []string{"1\n"},
+		},
+		{
+			name:            "tagged scalars which are not booleans are not equal to booleans",
+			input:           "[!!bool yes, true]",
+			path:            "$[?@ == true]",
+			expectedStrings: []string{"true\n"},
+		},
+		{
+			name:            "tagged scalars which are not numbers are not equal to themselves",
+			input:           "[!!int foo, !!bool yes, 1]",
+			path:            "$[?@ == @]",
+			expectedStrings: []string{"1\n"},
+		},
+		{
+			name:            "number with trailing dot",
+			path:            "$[?@ == 1.]",
+			expectedPathErr: `invalid number at position 10, following "@ == 1."`,
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.NewPathWithOptions(tc.path, yamlpath.RFC9535)
+			if tc.expectedPathErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedPathErr)
+				require.Nil(t, p)
+				return
+			}
+
+			var n yaml.Node
+			err = yaml.Unmarshal([]byte(tc.input), &n)
+			require.NoError(t, err)
+
+			actual, err := p.FindLocations(&n)
+			require.NoError(t, err)
+
+			actualStrings := []string{}
+			actualPaths := []string{}
+			for _, a := range actual {
+				var buf bytes.Buffer
+				e := yaml.NewEncoder(&buf)
+				e.SetIndent(2)
+
+				err = e.Encode(a.Node)
+				require.NoError(t, err)
+				e.Close()
+				actualStrings = append(actualStrings, buf.String())
+				actualPaths = append(actualPaths, a.Path)
+			}
+
+			require.Equal(t, tc.expectedStrings, actualStrings)
+			if tc.expectedPaths != nil {
+				require.Equal(t, tc.expectedPaths, actualPaths)
+			}
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
@@ -13,135 +13,191 @@ import (
 	"strings"
 )
 
-func slice(index string, length int) ([]int, error) {
+// parseSubscripts parses the content of an array subscript, for example `1`, `1:5:2`, `*`, or `0,2,-1:`, into
+// subscripts. A wildcard is parsed as a slice which selects all the indices. An integer which is too large in
+// magnitude to be represented is clamped, which does not affect the indices selected.
+func parseSubscripts(index string) ([]Subscript, error) {
 	if union := strings.Split(index, ","); len(union) > 1 {
-		combination := []int{}
-		for i, idx := range union {
+		subscripts := []Subscript{}
+		for i, member := range union {
 			// check wildcard, it cannot be used in union
-			if strings.TrimSpace(idx) == "*" {
+			if strings.TrimSpace(member) == "*" {
 				return nil, fmt.Errorf("error in union member %d: wildcard cannot be used in union", i)
 			}
-			sl, err := slice(idx, length)
+			s, err := parseSubscript(member)
 			if err != nil {
 				return nil, fmt.Errorf("error in union member %d: %s", i, err)
 			}
-			combination = append(combination, sl...)
+			subscripts = append(subscripts, s)
 		}
-		return combination, nil
+		return subscripts, nil
 	}
 
-	index = strings.TrimSpace(index)
+	if strings.TrimSpace(index) == "*" {
+		return []Subscript{{Slice: &Slice{}}}, nil
+	}
 
-	if index == "*" {
-		return indices(0, length, 1, length), nil
+	s, err := parseSubscript(index)
+	if err != nil {
+		return nil, err
 	}
+	return []Subscript{s}, nil
+}
 
-	subscr := strings.Split(index, ":")
-	if len(subscr) > 3 {
-		return nil, errors.New("malformed array index, too many colons")
-	}
-	type subscript struct {
-		present bool
-		value   int
-	}
-	var subscripts []subscript = []subscript{{false, 0}, {false, 0}, {false, 0}}
-	const (
-		sFrom = iota
-		sTo
-		sStep
-	)
-	for i, s := range subscr {
-		s = strings.TrimSpace(s)
-		if s != "" {
-			n, err := strconv.Atoi(s)
-			if err != nil {
-				return nil, errors.New("non-integer array index")
-			}
-			subscripts[i] = subscript{
-				present: true,
-				value:   n,
+// parseSubscript parses an index or a slice.
+func parseSubscript(index string) (Subscript, error) {
+	bounds := strings.Split(index, ":")
+	if len(bounds) > 3 {
+		return Subscript{}, errors.New("malformed array index, too many colons")
+	}
+	values := []*int{}
+	for _, b := range bounds {
+		b = strings.TrimSpace(b)
+		if b == "" {
+			values = append(values, nil)
+			continue
+		}
+		n, err := strconv.ParseInt(b, 10, 0)
+		if err != nil {
+			if ne, ok := err.(*strconv.NumError); !ok || ne.Err != strconv.ErrRange {
+				return Subscript{}, errors.New("non-integer array index")
 			}
 		}
+		i := int(n)
+		values = append(values, &i)
 	}
 
-	// pick out the case of a single subscript first since the "to" value needs special-casing
-	if len(subscr) == 1 {
-		if !subscripts[sFrom].present {
-			return nil, errors.New("array index missing")
-		}
-		from := subscripts[sFrom].value
-		if from < 0 {
-			from += length
+	if len(values) == 1 {
+		if values[0] == nil {
+			return Subscript{}, errors.New("array index missing")
 		}
-		return indices(from, from+1, 1, length), nil
+		return Subscript{Index: *values[0]}, nil
+	}
+	s := &Slice{Start: values[0], End: values[1]}
+	if len(values) == 3 {
+		s.Step = values[2]
 	}
+	return Subscript{Slice: s}, nil
+}
 
-	var from, to, step int
+// indexIterator iterates over indices of the items of a sequence in the manner of yit.Iterator.
+type indexIterator func() (int, bool)
 
-	if subscripts[sStep].present {
-		step = subscripts[sStep].value
-		if step == 0 {
-			return nil, errors.New("array index step value must be non-zero")
+func fromIndices(indices ...int) indexIterator {
+	i := 0
+	return func() (int, bool) {
+		if i >= len(indices) {
+			return 0, false
 		}
-	} else {
-		step = 1
+		i++
+		return indices[i-1], true
 	}
+}
 
-	if subscripts[sFrom].present {
-		from = subscripts[sFrom].value
-		if from < 0 {
-			from += length
-		}
-	} else {
-		if step > 0 {
-			from = 0
-		} else {
-			from = length - 1
+// subscriptIndices returns an iterator over the indices selected by the given subscripts from a sequence of the given
+// length, in the order of the subscripts. The indices are generated lazily, so that a slice with a large range does
+// not need a correspondingly large allocation.
+func subscriptIndices(subscripts []Subscript, length int) indexIterator {
+	var current indexIterator
+	return func() (int, bool) {
+		for {
+			if current != nil {
+				if i, ok := current(); ok {
+					return i, true
+				}
+			}
+			if len(subscripts) == 0 {
+				return 0, false
+			}
+			current = subscripts[0].indices(length)
+			subscripts = subscripts[1:]
 		}
 	}
+}
 
-	if subscripts[sTo].present {
-		to = subscripts[sTo].value
-		if to < 0 {
-			to += length
+// indices returns an iterator over the indices selected by the subscript from a sequence of the given length. A
+// negative index counts back from the end of the sequence and an index which is out of range selects nothing.
+func (s Subscript) indices(length int) indexIterator {
+	if s.Slice == nil {
+		i := s.Index
+		if i < 0 {
+			i += length
 		}
-	} else {
-		if step > 0 {
-			to = length
-		} else {
-			to = -1
+		if i < 0 || i >= length {
+			return fromIndices()
 		}
+		return fromIndices(i)
 	}
-
-	return indices(from, to, step, length), nil
+	bound := func(b *int) *int64 {
+		if b == nil {
+			return nil
+		}
+		v := int64(*b)
+		return &v
+	}
+	return normalizedSlice(bound(s.Slice.Start), bound(s.Slice.End), bound(s.Slice.Step), length)
 }
 
-func indices(from, to, step, length int) []int {
-	slice := []int{}
-	if step > 0 {
-		if from < 0 {
-			from = 0 // avoid CPU attack
-		}
-		if to > length {
-			to = length // avoid CPU attack
-		}
-		for i := from; i < to; i += step {
-			if 0 <= i && i < length {
-				slice = append(slice, i)
-			}
+// normalizedSlice returns an iterator over the indices selected by a slice with the given (optional) start, end, and
+// step values from a sequence of the given length using the normalization algorithm of RFC 9535. A step of zero
+// selects no indices.
+func normalizedSlice(start, end, step *int64, length int) indexIterator {
+	n := int64(length)
+	st := int64(1)
+	if step != nil {
+		st = *step
+	}
+	if st == 0 {
+		return fromIndices()
+	}
+
+	normalize := func(i int64) int64 {
+		if i < 0 {
+			return n + i
 		}
-	} else if step < 0 {
-		if from > length {
-			from = length // avoid CPU attack
+		return i
+	}
+	clamp := func(i, min, max int64) int64 {
+		if i < min {
+			return min
 		}
-		if to < -1 {
-			to = -1 // avoid CPU attack
+		if i > max {
+			return max
 		}
-		for i := from; i > to; i += step {
-			if 0 <= i && i < length {
-				slice = append(slice, i)
-			}
+		return i
+	}
+
+	// i is the next index and stop is the bound, which is not selected, in the direction of the step
+	var i, stop int64
+	if st > 0 {
+		i, stop = 0, n
+		if start != nil {
+			i = clamp(normalize(*start), 0, n)
+		}
+		if end != nil {
+			stop = clamp(normalize(*end), 0, n)
+		}
+	} else {
+		i, stop = n-1, -1
+		if start != nil {
+			i = clamp(normalize(*start), -1, n-1)
+		}
+		if end != nil {
+			stop = clamp(normalize(*end), -1, n-1)
+		}
+	}
+
+	return func() (int, bool) {
+		if st > 0 && i >= stop || st < 0 && i <= stop {
+			return 0, false
+		}
+		next := int(i)
+		// avoid overflow when the step is large
+		if st > 0 && st > stop-i || st < 0 && st < stop-i {
+			i = stop
+		} else {
+			i += st
 		}
+		return next, true
 	}
-	return slice
 }
@@ -201,10 +201,10 @@ func TestSlicer(t *testing.T) {
 			expectedErr: "non-integer array index",
 		},
 		{
-			name:        "zero step",
-			index:       "1:2:0",
-			length:      10,
-			expectedErr: "array index step value must be non-zero",
+			name:     "zero step",
+			index:    "1:2:0",
+			length:   10,
+			expected: []int{},
 		},
 		{
 			name:     "empty range",
@@ -292,6 +292,36 @@ func TestSlicer(t *testing.T) {
 			length:   10,
 			expected: []int{3, 2, 1, 0},
 		},
+		{
+			name:     "excessively large step",
+			index:    "1::9223372036854775807",
+			length:   10,
+			expected: []int{1},
+		},
+		{
+			name:     "excessively large negative step",
+			index:    "::-9223372036854775808",
+			length:   10,
+			expected: []int{9},
+		},
+		{
+			name:     "index too large to represent",
+			index:    "99999999999999999999",
+			length:   10,
+			expected: []int{},
+		},
+		{
+			name:     "bounds too large to represent",
+			index:    "-99999999999999999999:99999999999999999999",
+			length:   3,
+			expected: []int{0, 1, 2},
+		},
+		{
+			name:     "union of slices",
+			index:    "::-1,1:",
+			length:   3,
+			expected: []int{2, 1, 0, 1, 2},
+		},
 	}
 
 	focussed := false
@@ -306,14 +336,19 @@ func TestSlicer(t *testing.T) {
 		if focussed && !tc.focus {
 			continue
 		}
-		actual, err := slice(tc.index, tc.length)
 		t.Run(tc.name, func(t *testing.T) {
+			subscripts, err := parseSubscripts(tc.index)
 			if tc.expectedErr == "" {
 				require.NoError(t, err)
 			} else {
 				require.EqualError(t, err, tc.expectedErr)
 				return
 			}
+			actual := []int{}
+			it := subscriptIndices(subscripts, tc.length)
+			for i, ok := it(); ok; i, ok = it() {
+				actual = append(actual, i)
+			}
 			require.Equal(t, tc.expected, actual)
 		})
 	}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentMatch is a node which matches a Path in a document of a stream.
+type DocumentMatch struct {
+	// Document is the index of the document in the stream, starting from zero.
+	Document int
+
+	// Node is the matching node.
+	Node *yaml.Node
+}
+
+// SelectDocuments is an Option which restricts FindInDocuments and FindInStream to the documents selected by the
+// given path expression. The expression is applied to a sequence whose items are the documents of the stream, so
+// `$[0]` selects the first document, `$[1:]` all the documents after the first, and
+// `$[?(@.kind == 'Deployment')]` the documents whose kind is Deployment. The expression is parsed with the other
+// options. SelectDocuments does not affect Find or the other methods of Path.
+func SelectDocuments(selector string) Option {
+	return func(o *options) {
+		o.documents = &selector
+	}
+}
+
+// withDocumentSelector compiles the document selector, if any, in the given options and records it in the Path.
+func (p *Path) withDocumentSelector(o *options) (*Path, error) {
+	if o.documents == nil {
+		return p, nil
+	}
+	so := *o
+	so.documents = nil
+	var (
+		s   *Path
+		err error
+	)
+	if so.rfc9535 {
+		s, err = newRFC9535Path(*o.documents, &so)
+	} else {
+		s, err = compile(*o.documents, &so)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid document selector: %w", err)
+	}
+	p.documents = s
+	return p, nil
+}
+
+// FindInDocuments applies the Path to each of the given document nodes, or to those selected by the SelectDocuments
+// option, and returns the matching nodes, in order of document, together with the index of the document in which
+// each node matched.
+func (p *Path) FindInDocuments(docs []*yaml.Node) ([]DocumentMatch, error) {
+	matches := []DocumentMatch{}
+	for _, i := range p.selectDocuments(docs) {
+		next := p.find(docs[i])
+		for l, ok := next(); ok; l, ok = next() {
+			matches = append(matches, DocumentMatch{Document: i, Node: l.node})
+		}
+	}
+	return matches, nil // currently, errors are not possible
+}
+
+// FindInStream decodes all the documents from the given decoder and then behaves like FindInDocuments. An error is
+// returned if a document cannot be decoded.
+func (p *Path) FindInStream(d *yaml.Decoder) ([]DocumentMatch, error) {
+	docs := []*yaml.Node{}
+	for {
+		var doc yaml.Node
+		if err := d.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+	return p.FindInDocuments(docs)
+}
+
+// selectDocuments returns, in ascending order, the indices of the documents selected by the document selector or,
+// if there is no document selector, the indices of all the documents.
+func (p *Path) selectDocuments(docs []*yaml.Node) []int {
+	indices := []int{}
+	if p.documents == nil {
+		for i := range docs {
+			indices = append(indices, i)
+		}
+		return indices
+	}
+
+	stream := &yaml.Node{
+		Kind: yaml.SequenceNode,
+		Tag:  "!!seq",
+	}
+	for _, doc := range docs {
+		stream.Content = append(stream.Content, unwrapDocument(doc))
+	}
+	selected := map[*yaml.Node]bool{}
+	next := p.documents.find(stream)
+	for l, ok := next(); ok; l, ok = next() {
+		selected[l.node] = true
+	}
+	for i, n := range stream.Content {
+		if selected[n] {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindInStream(t *testing.T) {
+	input := `kind: Deployment
+metadata: {name: web}
+---
+kind: Service
+metadata: {name: web}
+---
+kind: Deployment
+metadata: {name: worker}
+`
+
+	cases := []struct {
+		name        string
+		path        string
+		options     []yamlpath.Option
+		input       string   // if empty, input above is used
+		expected    []string // each match as document index and value, e.g. "0:web"
+		expectedErr string
+		focus       bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:     "all documents",
+			path:     "$.metadata.name",
+			expected: []string{"0:web", "1:web", "2:worker"},
+		},
+		{
+			name:     "no matches",
+			path:     "$.spec",
+			expected: []string{},
+		},
+		{
+			name:     "documents selected by index",
+			path:     "$.kind",
+			options:  []yamlpath.Option{yamlpath.SelectDocuments("$[2,0,2]")},
+			expected: []string{"0:Deployment", "2:Deployment"},
+		},
+		{
+			name:     "documents selected by slice",
+			path:     "$.metadata.name",
+			options:  []yamlpath.Option{yamlpath.SelectDocuments("$[1:]")},
+			expected: []string{"1:web", "2:worker"},
+		},
+		{
+			name:     "documents selected by filter",
+			path:     "$.metadata.name",
+			options:  []yamlpath.Option{yamlpath.SelectDocuments("$[?(@.kind == 'Deployment')]")},
+			expected: []string{"0:web", "2:worker"},
+		},
+		{
+			name:     "documents selected by filter with RFC 9535",
+			path:     "$.metadata.name",
+			options:  []yamlpath.Option{yamlpath.RFC9535, yamlpath.SelectDocuments("$[?@.kind == 'Service']")},
+			expected: []string{"1:web"},
+		},
+		{
+			name:     "no documents selected",
+			path:     "$.kind",
+			options:  []yamlpath.Option{yamlpath.SelectDocuments("$[5]")},
+			expected: []string{},
+		},
+		{
+			name:     "empty stream",
+			path:     "$.kind",
+			input:    "# nothing here\n",
+			expected: []string{},
+		},
+		{
+			name:        "invalid document",
+			path:        "$.kind",
+			input:       "kind: a\n---\nkind: [\n",
+			expectedErr: "yaml: line 3: did not find expected node content",
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			require.NoError(t, err)
+
+			in := tc.input
+			if in == "" {
+				in = input
+			}
+			actual, err := p.FindInStream(yaml.NewDecoder(strings.NewReader(in)))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			matches := []string{}
+			for _, m := range actual {
+				matches = append(matches, fmt.Sprintf("%d:%s", m.Document, m.Node.Value))
+			}
+			require.Equal(t, tc.expected, matches)
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
+
+func TestFindInDocuments(t *testing.T) {
+	docs := []*yaml.Node{}
+	for _, doc := range []string{"a: 1", "b: 2", "a: 3"} {
+		var n yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte(doc), &n))
+		docs = append(docs, &n)
+	}
+
+	p, err := yamlpath.NewPathWithOptions("$.a", yamlpath.SelectDocuments("[?(@.a)]"))
+	require.NoError(t, err)
+
+	actual, err := p.FindInDocuments(docs)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	require.Equal(t, 0, actual[0].Document)
+	require.Same(t, docs[0].Content[0].Content[1], actual[0].Node)
+	require.Equal(t, 2, actual[1].Document)
+	require.Same(t, docs[2].Content[0].Content[1], actual[1].Node)
+
+	// the document selector does not affect Find
+	found, err := p.Find(docs[1])
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
+
+func TestSelectDocumentsInvalid(t *testing.T) {
+	_, err := yamlpath.NewPathWithOptions("$.a", yamlpath.SelectDocuments("$[?(@.a ==)]"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid document selector: ")
+
+	var serr *yamlpath.SyntaxError
+	require.True(t, errors.As(err, &serr))
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError is returned by NewPath and NewPathWithOptions when an error is detected while scanning a path
+// expression. Errors detected after scanning, such as type errors in the filters of a path expression in the syntax
+// described in the README, are reported using other error values.
+type SyntaxError struct {
+	// Expr is the path expression, or the portion of it, in which the error was detected.
+	Expr string
+
+	// Offset is the byte offset in Expr at which the error was detected.
+	Offset int
+
+	// Column is the column, counted in runes starting at 1, at which the error was detected.
+	Column int
+
+	// Token is the offending token: the portion of Expr which was being scanned when the error was detected or,
+	// if that is empty, the character at Offset. Token is empty if the error was detected at the end of Expr.
+	Token string
+
+	// Expected is the set of tokens which would have been valid at Offset, or nil if this is not known.
+	Expected []string
+
+	msg string
+}
+
+// newSyntaxError creates a syntax error with the given message for the given offset in an expression. The offending
+// token runs from start to end or, if start is not before end, consists of the character at offset.
+func newSyntaxError(msg, expr string, offset, start, end int, expected []string) *SyntaxError {
+	token := ""
+	if start < end {
+		token = expr[start:end]
+	} else if offset < len(expr) {
+		_, w := utf8.DecodeRuneInString(expr[offset:])
+		token = expr[offset : offset+w]
+	}
+	return &SyntaxError{
+		Expr:     expr,
+		Offset:   offset,
+		Column:   utf8.RuneCountInString(expr[:offset]) + 1,
+		Token:    token,
+		Expected: expected,
+		msg:      msg,
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	return e.msg
+}
+
+// Excerpt returns Expr followed by a line containing a caret which points at Column, for example:
+//
+//	$.a[?(@.b == )]
+//	             ^
+func (e *SyntaxError) Excerpt() string {
+	return e.Expr + "\n" + strings.Repeat(" ", e.Column-1) + "^"
+}
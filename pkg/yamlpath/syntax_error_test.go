@@ -0,0 +1,138 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+)
+
+func TestSyntaxError(t *testing.T) {
+	cases := []struct {
+		name             string
+		path             string
+		options          []yamlpath.Option
+		expectedOffset   int
+		expectedColumn   int
+		expectedToken    string
+		expectedExpected []string
+		expectedExcerpt  string
+		focus            bool // if true, run only tests with focus set to true
+	}{
+		{
+			name:             "missing end of bracket child",
+			path:             "$.a['b'",
+			expectedOffset:   7,
+			expectedColumn:   8,
+			expectedToken:    "['b'",
+			expectedExpected: []string{"]", ","},
+			expectedExcerpt: `$.a['b'
+       ^`,
+		},
+		{
+			name:            "invalid filter expression",
+			path:            "$.a[?(@.b == 1]",
+			expectedOffset:  14,
+			expectedColumn:  15,
+			expectedToken:   "]",
+			expectedExcerpt: "$.a[?(@.b == 1]\n              ^",
+		},
+		{
+			name:            "unmatched string delimiter",
+			path:            `$.a[?(@.b == "x)]`,
+			expectedOffset:  13,
+			expectedColumn:  14,
+			expectedToken:   `"x)]`,
+			expectedExcerpt: "$.a[?(@.b == \"x)]\n             ^",
+		},
+		{
+			name:            "invalid regular expression",
+			path:            "$[?(@.x =~ /a(/)]",
+			expectedOffset:  11,
+			expectedColumn:  12,
+			expectedToken:   "/a(/",
+			expectedExcerpt: "$[?(@.x =~ /a(/)]\n           ^",
+		},
+		{
+			name:            "invalid array index",
+			path:            "$[1:a]",
+			expectedOffset:  6,
+			expectedColumn:  7,
+			expectedToken:   "[1:a]",
+			expectedExcerpt: "$[1:a]\n      ^",
+		},
+		{
+			name:            "multibyte characters",
+			path:            "$.é.[",
+			expectedOffset:  5,
+			expectedColumn:  5,
+			expectedToken:   ".",
+			expectedExcerpt: "$.é.[\n    ^",
+		},
+		{
+			name:             "RFC 9535 missing close bracket",
+			path:             "$.a[?@.b == 1",
+			options:          []yamlpath.Option{yamlpath.RFC9535},
+			expectedOffset:   13,
+			expectedColumn:   14,
+			expectedExpected: []string{"]", ","},
+			expectedExcerpt:  "$.a[?@.b == 1\n             ^",
+		},
+		{
+			name:             "RFC 9535 missing root",
+			path:             "a",
+			options:          []yamlpath.Option{yamlpath.RFC9535},
+			expectedOffset:   0,
+			expectedColumn:   1,
+			expectedToken:    "a",
+			expectedExpected: []string{"$"},
+			expectedExcerpt:  "a\n^",
+		},
+		{
+			name:             "RFC 9535 multibyte characters",
+			path:             "$['é'x]",
+			options:          []yamlpath.Option{yamlpath.RFC9535},
+			expectedOffset:   6,
+			expectedColumn:   6,
+			expectedToken:    "x",
+			expectedExpected: []string{"]", ","},
+			expectedExcerpt:  "$['é'x]\n     ^",
+		},
+	}
+
+	focussed := false
+	for _, tc := range cases {
+		if tc.focus {
+			focussed = true
+			break
+		}
+	}
+
+	for _, tc := range cases {
+		if focussed && !tc.focus {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := yamlpath.NewPathWithOptions(tc.path, tc.options...)
+			var se *yamlpath.SyntaxError
+			require.True(t, errors.As(err, &se), "error %v is not a syntax error", err)
+			require.Equal(t, tc.path, se.Expr)
+			require.Equal(t, tc.expectedOffset, se.Offset)
+			require.Equal(t, tc.expectedColumn, se.Column)
+			require.Equal(t, tc.expectedToken, se.Token)
+			require.Equal(t, tc.expectedExpected, se.Expected)
+			require.Equal(t, tc.expectedExcerpt, se.Excerpt())
+		})
+	}
+
+	if focussed {
+		t.Fatalf("testcase(s) still focussed")
+	}
+}
@@ -8,16 +8,29 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
 	"gopkg.in/yaml.v3"
 )
 
+// evaluationTimeout and evaluationLimits bound the resources used to evaluate a JSON path against a YAML document.
+const evaluationTimeout = 5 * time.Second
+
+var evaluationLimits = yamlpath.Limits{
+	MaxVisitedNodes: 1000000,
+	MaxResults:      10000,
+	MaxDepth:        1000,
+	MaxRegexpSize:   10000,
+}
+
 func main() {
 	tmpl := template.New("template")
 	tmpl, err := tmpl.Parse(`<style type="text/css">
@@ -56,6 +69,7 @@ textarea, input {
 (<a href="https://github.com/vmware-labs/yaml-jsonpath/tree/{{ .Version }}#syntax" target="_blank">syntax</a>):<br />
 <pre>
 <input type="text" size="80" name="JSON path" placeholder="JSON path..." value="{{ .JSONPath }}"><br />
+<input type="checkbox" id="comments" name="Comments" value="on"{{if .Comments}} checked{{end}}><label for="comments">Output comments instead of matching nodes</label><br />
 <input type="submit" value="Evaluate">
 </pre>
 </form>
@@ -72,6 +86,14 @@ textarea, input {
 {{end}}
 {{if .JSONPathError}}
     <br />Invalid JSON path: {{ .JSONPathError }}<br />
+{{if .JSONPathErrorExcerpt}}
+<pre>
+{{ .JSONPathErrorExcerpt }}
+</pre>
+{{end}}
+{{end}}
+{{if .EvaluationError}}
+    <br />Evaluation failed: {{ .EvaluationError }}<br />
 {{end}}
 <pre>
 {{ .Output }}<br />
@@ -87,13 +109,16 @@ textarea, input {
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		type output struct {
-			YAML          string
-			YAMLError     error
-			JSONPath      string
-			JSONPathError error
-			Success       bool
-			Output        string
-			Version       string
+			YAML                 string
+			YAMLError            error
+			JSONPath             string
+			Comments             bool
+			JSONPathError        error
+			JSONPathErrorExcerpt string
+			EvaluationError      error
+			Success              bool
+			Output               string
+			Version              string
 		}
 
 		op := output{
@@ -120,10 +145,15 @@ textarea, input {
 
 		j := r.FormValue("JSON path")
 		op.JSONPath = j
+		op.Comments = r.FormValue("Comments") != ""
 		path, err := yamlpath.NewPath(j)
 		if err != nil {
 			problem = true
 			op.JSONPathError = err
+			var se *yamlpath.SyntaxError
+			if errors.As(err, &se) {
+				op.JSONPathErrorExcerpt = se.Excerpt()
+			}
 		}
 
 		if problem {
@@ -133,14 +163,24 @@ textarea, input {
 			return
 		}
 
-		results, err := path.Find(&n)
+		ctx, cancel := context.WithTimeout(r.Context(), evaluationTimeout)
+		defer cancel()
+		results, err := path.FindLocationsContext(ctx, &n, evaluationLimits)
 		if err != nil {
-			respondWithError(w, err)
+			op.EvaluationError = err
+			if e := tmpl.Execute(w, op); e != nil {
+				respondWithError(w, e)
+			}
+			return
 		}
 
 		out := []string{}
 		for _, a := range results {
-			b, err := encode(a)
+			if op.Comments {
+				out = append(out, comments(a))
+				continue
+			}
+			b, err := encode(a.Node)
 			if err != nil {
 				respondWithError(w, err)
 				return
@@ -180,6 +220,14 @@ func encode(a *yaml.Node) (string, error) {
 	return buf.String(), nil
 }
 
+// comments formats the normalized path and comments of a matching node.
+func comments(l yamlpath.Location) string {
+	if l.Comment == "" {
+		return l.Path + "\n(no comments)\n"
+	}
+	return l.Path + "\n" + l.Comment + "\n"
+}
+
 func respondWithError(w http.ResponseWriter, err error) {
 	log.Println(err)
 	http.Error(w, err.Error(), http.StatusInternalServerError)